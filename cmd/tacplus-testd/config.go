@@ -0,0 +1,110 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// Actions of the command rules
+const (
+	actionPermit = "permit"
+	actionDeny   = "deny"
+)
+
+// Config is the configuration file of the daemon
+type Config struct {
+	Secret string           `json:"secret"` // Shared secret of the clients
+	Users  map[string]*User `json:"users"`
+}
+
+// User is the account of a user and the commands it may run
+type User struct {
+	Password string  `json:"password"`
+	PrivLvl  int     `json:"priv_lvl"`
+	Role     string  `json:"role,omitempty"`     // Sent with priv-lvl when set
+	Commands []*Rule `json:"commands,omitempty"` // Tried in order, the first matching rule decides
+	Default  string  `json:"default,omitempty"`  // Action of the commands no rule matches, deny when not set
+}
+
+// Rule permits or denies the commands matching a regular expression, matched
+// against the command followed by its arguments
+type Rule struct {
+	Match  string `json:"match"`
+	Action string `json:"action"`
+	match  *regexp.Regexp
+}
+
+// Reads and checks a configuration file
+func loadConfig(path string) (*Config, error) {
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("Invalid configuration %s: %v", path, err)
+	}
+
+	if config.Secret == "" {
+		return nil, errors.New("No secret configured")
+	}
+
+	for name, user := range config.Users {
+
+		if user.PrivLvl < 0 || user.PrivLvl > 15 {
+			return nil, fmt.Errorf("Invalid priv_lvl %d of user %s", user.PrivLvl, name)
+		}
+
+		if user.Default != "" && user.Default != actionPermit && user.Default != actionDeny {
+			return nil, fmt.Errorf("Invalid default action %s of user %s", user.Default, name)
+		}
+
+		for _, rule := range user.Commands {
+
+			if rule.Action != actionPermit && rule.Action != actionDeny {
+				return nil, fmt.Errorf("Invalid action %s of user %s", rule.Action, name)
+			}
+
+			if rule.match, err = regexp.Compile(rule.Match); err != nil {
+				return nil, fmt.Errorf("Invalid command match %s of user %s: %v", rule.Match, name, err)
+			}
+		}
+	}
+
+	return config, nil
+}
+
+// Returns whether the user may run the command
+func (u *User) permits(cmd string, cmdArgs string) bool {
+
+	command := strings.TrimSpace(cmd + " " + cmdArgs)
+
+	for _, rule := range u.Commands {
+		if rule.match.MatchString(command) {
+			return rule.Action == actionPermit
+		}
+	}
+
+	return u.Default == actionPermit
+}
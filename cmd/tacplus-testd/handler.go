@@ -0,0 +1,155 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"orange/sonic-netconf-server/tacplus"
+
+	"github.com/golang/glog"
+)
+
+// handler answers the requests with the users of the configuration and writes
+// the accounting records to accounting
+type handler struct {
+	config     *Config
+	accounting io.Writer
+	lock       sync.Mutex // Serializes the accounting records of concurrent sessions
+}
+
+func (h *handler) HandleAuthenStart(ctx context.Context, a *tacplus.AuthenStart, s *tacplus.ServerSession) *tacplus.AuthenReply {
+
+	user, ok := h.config.Users[a.User]
+	passed := false
+
+	switch a.AuthenType {
+	case tacplus.AuthenTypeASCII, tacplus.AuthenTypePAP:
+		password := string(a.Data)
+		// An ASCII login without data prompts for the password
+		if password == "" && a.AuthenType == tacplus.AuthenTypeASCII {
+			c, err := s.GetPass(ctx, "Password: ")
+			if err != nil || c.Abort {
+				return nil
+			}
+			password = c.Message
+		}
+		passed = ok && password == user.Password
+	case tacplus.AuthenTypeCHAP:
+		passed = ok && tacplus.VerifyCHAP(a.Data, user.Password)
+	case tacplus.AuthenTypeMSCHAP:
+		passed = ok && tacplus.VerifyMSCHAP(a.Data, user.Password)
+	case tacplus.AuthenTypeMSCHAPv2:
+		passed = ok && tacplus.VerifyMSCHAPv2(a.Data, a.User, user.Password)
+	default:
+		glog.Warningf("Authentication type %d of user (%s) not supported", a.AuthenType, a.User)
+		return &tacplus.AuthenReply{Status: tacplus.AuthenStatusError, ServerMsg: "Authentication type not supported"}
+	}
+
+	glog.Infof("Authentication of user (%s) port (%s) rem_addr (%s) type %d: %t", a.User, a.Port, a.RemAddr, a.AuthenType, passed)
+
+	if !passed {
+		return &tacplus.AuthenReply{Status: tacplus.AuthenStatusFail}
+	}
+
+	return &tacplus.AuthenReply{Status: tacplus.AuthenStatusPass}
+}
+
+/*Authorizes the session of a user, with its priv-lvl and role, when the request has no command,
+or a command with the rules of the user
+*/
+func (h *handler) HandleAuthorRequest(ctx context.Context, a *tacplus.AuthorRequest, s *tacplus.ServerSession) *tacplus.AuthorResponse {
+
+	user, ok := h.config.Users[a.User]
+
+	if !ok {
+		glog.Infof("Authorization of unknown user (%s) denied", a.User)
+		return &tacplus.AuthorResponse{Status: tacplus.AuthorStatusFail}
+	}
+
+	args := argValues(a.Arg)
+	cmd, cmdArgs := args["cmd"], args["cmd-arg"]
+
+	if cmd == "" {
+		glog.Infof("Session of user (%s) port (%s) rem_addr (%s) authorized at priv-lvl %d", a.User, a.Port, a.RemAddr, user.PrivLvl)
+
+		response := &tacplus.AuthorResponse{Status: tacplus.AuthorStatusPassAdd, Arg: []string{"priv-lvl=" + strconv.Itoa(user.PrivLvl)}}
+		if user.Role != "" {
+			response.Arg = append(response.Arg, "role="+user.Role)
+		}
+		return response
+	}
+
+	permitted := user.permits(cmd, cmdArgs)
+
+	glog.Infof("Command (%s %s) of user (%s) port (%s) permitted: %t", cmd, cmdArgs, a.User, a.Port, permitted)
+
+	if !permitted {
+		return &tacplus.AuthorResponse{Status: tacplus.AuthorStatusFail}
+	}
+
+	return &tacplus.AuthorResponse{Status: tacplus.AuthorStatusPassAdd}
+}
+
+// Writes an accounting record, with the time, client and flags of the request
+func (h *handler) HandleAcctRequest(ctx context.Context, a *tacplus.AcctRequest, s *tacplus.ServerSession) *tacplus.AcctReply {
+
+	record := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\n", time.Now().Format(time.RFC3339), a.RemAddr, a.User, a.Port, acctFlags(a.Flags), strings.Join(a.Arg, "\t"))
+
+	h.lock.Lock()
+	_, err := io.WriteString(h.accounting, record)
+	h.lock.Unlock()
+
+	if err != nil {
+		glog.Errorf("Unable to write the accounting record of user (%s): %v", a.User, err)
+		return &tacplus.AcctReply{Status: tacplus.AcctStatusError}
+	}
+
+	return &tacplus.AcctReply{Status: tacplus.AcctStatusSuccess}
+}
+
+// argValues returns the values of attribute-value pairs, mandatory or optional
+func argValues(args []string) map[string]string {
+
+	values := map[string]string{}
+
+	for _, arg := range args {
+		if i := strings.IndexAny(arg, "=*"); i > 0 {
+			values[arg[:i]] = arg[i+1:]
+		}
+	}
+
+	return values
+}
+
+// acctFlags returns the name of the record type of accounting flags
+func acctFlags(flags uint8) string {
+	switch {
+	case flags&tacplus.AcctFlagStart != 0 && flags&tacplus.AcctFlagWatchdog != 0:
+		return "update"
+	case flags&tacplus.AcctFlagStart != 0:
+		return "start"
+	case flags&tacplus.AcctFlagStop != 0:
+		return "stop"
+	case flags&tacplus.AcctFlagWatchdog != 0:
+		return "watchdog"
+	}
+	return "unknown"
+}
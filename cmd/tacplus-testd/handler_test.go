@@ -0,0 +1,145 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"orange/sonic-netconf-server/tacplus"
+)
+
+func init(){
+	fmt.Println("+++++ init handler_test +++++")
+}
+
+// startTestDaemon serves the example configuration on a local port until the
+// listener is closed
+func startTestDaemon(t *testing.T, accounting *bytes.Buffer) (*tacplus.Client, net.Listener) {
+
+	config, err := loadConfig("users.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	connHandler := &tacplus.ServerConnHandler{
+		Handler:    &handler{config: config, accounting: accounting},
+		ConnConfig: tacplus.ConnConfig{Secret: []byte(config.Secret), Mux: true},
+	}
+
+	go (&tacplus.Server{ServeConn: connHandler.Serve}).Serve(l)
+
+	addr := l.Addr().(*net.TCPAddr)
+
+	return tacplus.CreateClientFromInfo(&tacplus.TacacsInfo{IP: addr.IP.String(), Port: addr.Port, Password: config.Secret, Timeout: 1}), l
+}
+
+func TestAuthentication(t *testing.T) {
+
+	client, l := startTestDaemon(t, &bytes.Buffer{})
+	defer l.Close()
+
+	tests := []struct {
+		authenType uint8
+		user       string
+		password   string
+		correct    uint8
+	}{
+		{tacplus.AuthenTypePAP, "admin", "admin", tacplus.AuthenStatusPass},
+		{tacplus.AuthenTypePAP, "admin", "wrong", tacplus.AuthenStatusFail},
+		{tacplus.AuthenTypePAP, "unknown", "admin", tacplus.AuthenStatusFail},
+		{tacplus.AuthenTypeCHAP, "operator", "operator", tacplus.AuthenStatusPass},
+		{tacplus.AuthenTypeMSCHAPv2, "operator", "wrong", tacplus.AuthenStatusFail},
+		{tacplus.AuthenTypeARAP, "admin", "admin", tacplus.AuthenStatusError},
+	}
+
+	for _, test := range tests {
+
+		data, _ := tacplus.AuthenData(test.authenType, test.user, test.password)
+
+		reply, _, err := client.SendAuthenStart(context.Background(), &tacplus.AuthenStart{
+			Action:        tacplus.AuthenActionLogin,
+			AuthenType:    test.authenType,
+			AuthenService: tacplus.AuthenServicePPP,
+			User:          test.user,
+			Data:          data,
+		})
+
+		if err != nil || reply.Status != test.correct {
+			t.Errorf("Result was incorrect for %+v, got: %+v (%v), want: %d.", test, reply, err, test.correct)
+		}
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+
+	client, l := startTestDaemon(t, &bytes.Buffer{})
+	defer l.Close()
+
+	tests := []struct {
+		user    string
+		args    []string
+		correct uint8
+		reply   string
+	}{
+		{"operator", []string{"service=shell", "protocol=ssh", "cmd*"}, tacplus.AuthorStatusPassAdd, "priv-lvl=1"},
+		{"netops", []string{"service=shell", "protocol=ssh", "cmd*"}, tacplus.AuthorStatusPassAdd, "priv-lvl=15 role=admin"},
+		{"operator", []string{"service=shell", "cmd=get", "cmd-arg=/openconfig-interfaces:interfaces"}, tacplus.AuthorStatusPassAdd, ""},
+		{"operator", []string{"service=shell", "cmd=kill-session", "cmd-arg=/ietf-netconf:kill-session"}, tacplus.AuthorStatusFail, ""},
+		{"netops", []string{"service=shell", "cmd=get", "cmd-arg=/sonic-system-aaa:sonic-system-aaa"}, tacplus.AuthorStatusFail, ""},
+		{"netops", []string{"service=shell", "cmd=get", "cmd-arg=/openconfig-interfaces:interfaces"}, tacplus.AuthorStatusPassAdd, ""},
+		{"unknown", []string{"service=shell", "cmd*"}, tacplus.AuthorStatusFail, ""},
+	}
+
+	for _, test := range tests {
+
+		response, err := client.SendAuthorRequest(context.Background(), &tacplus.AuthorRequest{User: test.user, Arg: test.args})
+
+		if err != nil || response.Status != test.correct || strings.Join(response.Arg, " ") != test.reply {
+			t.Errorf("Result was incorrect for %+v, got: %+v (%v), want: %d %s.", test, response, err, test.correct, test.reply)
+		}
+	}
+}
+
+func TestAccounting(t *testing.T) {
+
+	accounting := &bytes.Buffer{}
+	client, l := startTestDaemon(t, accounting)
+	defer l.Close()
+
+	reply, err := client.SendAcctRequest(context.Background(), &tacplus.AcctRequest{
+		Flags:   tacplus.AcctFlagStart,
+		User:    "admin",
+		Port:    "netconf1",
+		RemAddr: "10.0.0.1",
+		Arg:     []string{"task_id=1", "service=shell"},
+	})
+
+	if err != nil || reply.Status != tacplus.AcctStatusSuccess {
+		t.Fatalf("Result was incorrect, got: %+v (%v), want: %d.", reply, err, tacplus.AcctStatusSuccess)
+	}
+
+	if result := accounting.String(); !strings.HasSuffix(result, "\t10.0.0.1\tadmin\tnetconf1\tstart\ttask_id=1\tservice=shell\n") {
+		t.Errorf("Result was incorrect, got: %q, want: a start record of admin.", result)
+	}
+}
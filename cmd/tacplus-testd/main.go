@@ -0,0 +1,110 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+/*
+tacplus-testd is a TACACS+ server for testing the NETCONF server AAA without a
+production TACACS+ server. Users, their priv-lvl, role and command rules are read
+from a JSON configuration file, see users.json, and the accounting records are
+written to a file or the standard output. The connections use TLS when a
+certificate is given.
+
+	tacplus-testd -config users.json -listen :4949 -accounting acct.log -logtostderr
+*/
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"io"
+	"net"
+	"os"
+
+	"orange/sonic-netconf-server/tacplus"
+
+	"github.com/golang/glog"
+)
+
+// Command line parameters
+var (
+	configPath     string // Users configuration file
+	listenAddress  string // Listen address
+	accountingPath string // Accounting records file
+	singleConnect  bool   // Accept the single-connection mode
+	tlsCert        string // TLS certificate, TLS disabled when not set
+	tlsKey         string // TLS private key
+	tlsCA          string // CA of the client certificates
+)
+
+func init() {
+	flag.StringVar(&configPath, "config", "users.json", "Users configuration file")
+	flag.StringVar(&listenAddress, "listen", ":49", "Listen address")
+	flag.StringVar(&accountingPath, "accounting", "", "File the accounting records are appended to, the standard output when not set")
+	flag.BoolVar(&singleConnect, "single_connection", true, "Accept the single-connection mode of the clients")
+	flag.StringVar(&tlsCert, "tls_cert", "", "TLS certificate file, the connections use TLS when set")
+	flag.StringVar(&tlsKey, "tls_key", "", "TLS private key file")
+	flag.StringVar(&tlsCA, "tls_ca", "", "CA certificates file the client certificates are verified with, client certificates are not required when not set")
+}
+
+func main() {
+
+	flag.Parse()
+
+	config, err := loadConfig(configPath)
+
+	if err != nil {
+		glog.Exitf("Unable to load the configuration: %v", err)
+	}
+
+	var accounting io.Writer = os.Stdout
+
+	if accountingPath != "" {
+		file, err := os.OpenFile(accountingPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			glog.Exitf("Unable to open the accounting file: %v", err)
+		}
+		defer file.Close()
+		accounting = file
+	}
+
+	listener, err := net.Listen("tcp", listenAddress)
+
+	if err != nil {
+		glog.Exitf("Unable to listen on %s: %v", listenAddress, err)
+	}
+
+	if tlsCert != "" {
+		tlsConfig, err := tacplus.ServerTLSConfig(tlsCert, tlsKey, tlsCA)
+		if err != nil {
+			glog.Exitf("Invalid TLS configuration: %v", err)
+		}
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	glog.Infof("Serving %d users on %s", len(config.Users), listener.Addr())
+
+	connHandler := &tacplus.ServerConnHandler{
+		Handler: &handler{config: config, accounting: accounting},
+		ConnConfig: tacplus.ConnConfig{
+			Secret: []byte(config.Secret),
+			Mux:    singleConnect,
+			Log:    func(v ...interface{}) { glog.Warning(v...) },
+		},
+	}
+
+	server := &tacplus.Server{
+		ServeConn: connHandler.Serve,
+		Log:       func(v ...interface{}) { glog.Error(v...) },
+	}
+
+	glog.Exit(server.Serve(listener))
+}
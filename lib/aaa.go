@@ -0,0 +1,207 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"orange/sonic-netconf-server/tacplus"
+
+	"github.com/golang/glog"
+)
+
+// Login methods of AAA|authentication
+const (
+	LoginLocal  = "local"
+	LoginTacacs = "tacacs+"
+)
+
+// Value of AAA|accounting login disabling accounting
+const AAADisable = "disable"
+
+var errLoginFailed = errors.New("Authentication failed")
+
+// AAAAuthentication is the login configuration of AAA|authentication
+type AAAAuthentication struct {
+	Login       []string // Methods tried in order
+	Failthrough bool     // Try the next method when the credentials are rejected
+	Fallback    bool     // Try local when none of the methods could be reached
+}
+
+// Returns the AAA|authentication configuration, local login when not configured
+func GetAAAAuthentication() AAAAuthentication {
+
+	config := AAAAuthentication{Login: []string{LoginLocal}}
+
+	aaa, err := tacplus.GetAAAConfig("authentication")
+
+	if err != nil {
+		glog.Warningf("[AAA] Unable to read AAA authentication, using local login: %v", err)
+		return config
+	}
+
+	if login := ParseLoginMethods(aaa["login"]); len(login) != 0 {
+		config.Login = login
+	}
+
+	config.Failthrough, _ = strconv.ParseBool(aaa["failthrough"])
+	config.Fallback, _ = strconv.ParseBool(aaa["fallback"])
+
+	return config
+}
+
+// AAAPolicy is the command authorization and accounting configuration of
+// AAA|authorization and AAA|accounting
+type AAAPolicy struct {
+	Authorization []string // Methods tried in order, the first reachable one decides
+	Accounting    []string // Methods all recording the sessions and commands
+}
+
+// Returns the AAA|authorization and AAA|accounting configuration, local
+// authorization and no accounting when not configured
+func GetAAAPolicy() AAAPolicy {
+
+	policy := AAAPolicy{Authorization: []string{LoginLocal}}
+
+	if authorization, err := tacplus.GetAAAConfig("authorization"); err != nil {
+		glog.Warningf("[AAA] Unable to read AAA authorization, using local authorization: %v", err)
+	} else if methods := ParseLoginMethods(authorization["login"]); len(methods) != 0 {
+		policy.Authorization = methods
+	}
+
+	if accounting, err := tacplus.GetAAAConfig("accounting"); err != nil {
+		glog.Warningf("[AAA] Unable to read AAA accounting, accounting disabled: %v", err)
+	} else if methods := ParseLoginMethods(accounting["login"]); !contains(methods, AAADisable) {
+		policy.Accounting = methods
+	}
+
+	return policy
+}
+
+// Splits an AAA login value such as "tacacs+,local"
+func ParseLoginMethods(login string) []string {
+	return strings.FieldsFunc(login, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// loginMethod authenticates with one method, an error when the method could not
+// give an answer and false when it rejected the credentials
+type loginMethod func(ctx context.Context, username string, password string, remoteAddress string, port string) (Authenticator, bool, error)
+
+var loginMethods = map[string]loginMethod{
+	LoginLocal:  localLogin,
+	LoginTacacs: tacacsLogin,
+}
+
+/*Authenticates a user with the login methods of the AAA configuration, as sshd does on SONiC.
+A method which can't be reached is skipped. Rejected credentials end the login unless failthrough is set.
+Returns the authenticator to use for the session and the name of the method which accepted the user.
+remoteAddress is the IP address of the client and port identifies the session, as sshd does with the tty
+*/
+func Login(ctx context.Context, config AAAAuthentication, username string, password string, remoteAddress string, port string) (Authenticator, string, error) {
+
+	methods := config.Login
+
+	// Fall back to local when every listed method is unavailable
+	if config.Fallback && !contains(methods, LoginLocal) {
+		methods = append(append([]string{}, methods...), LoginLocal)
+	}
+
+	reached := false
+
+	for i, method := range methods {
+
+		if i == len(config.Login) && reached {
+			break
+		}
+
+		login, ok := loginMethods[method]
+
+		if !ok {
+			glog.Warningf("[AAA] Unsupported login method (%s), skipping", method)
+			continue
+		}
+
+		authenticator, passed, err := login(ctx, username, password, remoteAddress, port)
+
+		if err != nil {
+			glog.Warningf("[AAA] Login method (%s) unavailable: %v", method, err)
+			continue
+		}
+
+		if passed {
+			glog.Infof("[AAA] User (%s) authenticated by (%s)", username, method)
+			return authenticator, method, nil
+		}
+
+		reached = true
+		glog.Infof("[AAA] User (%s) rejected by (%s)", username, method)
+
+		if !config.Failthrough {
+			return nil, method, errLoginFailed
+		}
+	}
+
+	return nil, "", errLoginFailed
+}
+
+func localLogin(ctx context.Context, username string, password string, remoteAddress string, port string) (Authenticator, bool, error) {
+	authenticator := NewPAMAuthenticator(username, password, remoteAddress)
+	return authenticator, authenticator.Authenticate(), nil
+}
+
+func tacacsLogin(ctx context.Context, username string, password string, remoteAddress string, port string) (Authenticator, bool, error) {
+
+	authenticator, err := NewTacacsAuthenticator(ctx, "ssh", "shell", username, password, remoteAddress, port)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	passed, err := authenticator.authenticate()
+
+	if err != nil || !passed {
+		return nil, false, err
+	}
+
+	authenticator, passed, err = authenticator.authorizeLogin()
+
+	if err != nil {
+		glog.Warningf("[AAA] Login authorization unavailable for user (%s), using role %s: %v", username, authenticator.Role(), err)
+	} else if !passed {
+		glog.Infof("[AAA] Login of user (%s) not authorized", username)
+		return nil, false, nil
+	}
+
+	return authenticator, true, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func init(){
+	fmt.Println("+++++ init aaa_test +++++")
+}
+
+// withTestLoginMethods replaces the login methods by methods answering with the
+// result of results, "pass", "reject" or "down", and records the methods tried
+func withTestLoginMethods(results map[string]string, f func(tried *[]string)) {
+
+	saved := loginMethods
+	defer func() { loginMethods = saved }()
+
+	tried := []string{}
+	loginMethods = map[string]loginMethod{}
+
+	for _, name := range []string{LoginLocal, LoginTacacs} {
+		method := name
+		loginMethods[method] = func(ctx context.Context, username string, password string, remoteAddress string, port string) (Authenticator, bool, error) {
+			tried = append(tried, method)
+			switch results[method] {
+			case "pass":
+				return NewPAMAuthenticator(username, password, remoteAddress), true, nil
+			case "down":
+				return nil, false, errors.New("unreachable")
+			}
+			return nil, false, nil
+		}
+	}
+
+	f(&tried)
+}
+
+func TestParseLoginMethods(t *testing.T) {
+
+	result := ParseLoginMethods("tacacs+,local")
+
+	if !reflect.DeepEqual(result, []string{LoginTacacs, LoginLocal}) {
+		t.Errorf("Result was incorrect, got: %v, want: %v.", result, []string{LoginTacacs, LoginLocal})
+	}
+}
+
+func TestLogin(t *testing.T) {
+
+	tests := []struct {
+		login       string
+		failthrough bool
+		fallback    bool
+		results     map[string]string
+		method      string
+		tried       string
+	}{
+		{"tacacs+ local", false, false, map[string]string{LoginTacacs: "pass"}, LoginTacacs, "tacacs+"},
+		{"tacacs+ local", false, false, map[string]string{LoginTacacs: "reject", LoginLocal: "pass"}, "", "tacacs+"},
+		{"tacacs+ local", true, false, map[string]string{LoginTacacs: "reject", LoginLocal: "pass"}, LoginLocal, "tacacs+,local"},
+		{"tacacs+ local", false, false, map[string]string{LoginTacacs: "down", LoginLocal: "pass"}, LoginLocal, "tacacs+,local"},
+		{"tacacs+", false, false, map[string]string{LoginTacacs: "down", LoginLocal: "pass"}, "", "tacacs+"},
+		{"tacacs+", false, true, map[string]string{LoginTacacs: "down", LoginLocal: "pass"}, LoginLocal, "tacacs+,local"},
+		{"tacacs+", true, true, map[string]string{LoginTacacs: "reject", LoginLocal: "pass"}, "", "tacacs+"},
+		{"radius,local", false, false, map[string]string{LoginLocal: "pass"}, LoginLocal, "local"},
+	}
+
+	for _, test := range tests {
+		withTestLoginMethods(test.results, func(tried *[]string) {
+
+			config := AAAAuthentication{Login: ParseLoginMethods(test.login), Failthrough: test.failthrough, Fallback: test.fallback}
+
+			authenticator, method, err := Login(context.Background(), config, "admin", "password", "10.0.0.1", "netconf1")
+
+			if test.method == "" {
+				if err == nil {
+					t.Errorf("Result was incorrect for %+v, got: %s, want: an authentication failure.", test, method)
+				}
+			} else if err != nil || authenticator == nil || method != test.method {
+				t.Errorf("Result was incorrect for %+v, got: %s (%v), want: %s.", test, method, err, test.method)
+			}
+
+			if result := strings.Join(*tried, ","); result != test.tried {
+				t.Errorf("Result was incorrect for %+v, got: %s, want: %s.", test, result, test.tried)
+			}
+		})
+	}
+}
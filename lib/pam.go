@@ -21,52 +21,67 @@ package lib
 
 import (
 	"github.com/golang/glog"
-	"golang.org/x/crypto/ssh"
 )
 
+// PAM service of the local logins. The netconf service of the package only
+// checks the local accounts, a service running pam_tacplus would repeat the
+// TACACS+ login and defeat the fallback to the local logins
+var PAMService = "netconf"
+
+// PAMBackend runs the PAM transactions authenticating a user or checking its account
+type PAMBackend interface {
+	Authenticate(service string, username string, password string, remoteAddress string) error
+	CheckAccount(service string, username string, remoteAddress string) error
+}
+
+// PAM backend of the local logins, the system PAM library unless replaced,
+// e.g. by a fake in tests
+var PAMLibrary PAMBackend = systemPAM{}
+
 type PAMAuthenticator struct {
-	username string
-	password string
+	username      string
+	password      string
+	remoteAddress string
 }
 
-func NewPAMAuthenticator(username string, password string) PAMAuthenticator {
+func NewPAMAuthenticator(username string, password string, remoteAddress string) PAMAuthenticator {
 	return PAMAuthenticator{
-		username: username,
-		password: password,
+		username:      username,
+		password:      password,
+		remoteAddress: remoteAddress,
 	}
 }
 
+// Authenticates the user with the PAM service
 func (p PAMAuthenticator) Authenticate() bool {
-	var rc struct{
-		ID string
-	}
-
-	rc.ID = p.username
 
-	glog.Infof("[%s] Received user=%s", rc.ID, p.username)
+	glog.Infof("[PAM] Authenticating user (%s) from (%s) with service (%s)", p.username, p.remoteAddress, PAMService)
 
-	//Use ssh for authentication.
-	config := &ssh.ClientConfig{
-		User: p.username,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(p.password),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-	}
-	_, err := ssh.Dial("tcp", "127.0.0.1:22", config)
-	if err != nil {
-		glog.Infof("[%s] Failed to authenticate; %v", rc.ID, err)
+	if err := PAMLibrary.Authenticate(PAMService, p.username, p.password, p.remoteAddress); err != nil {
+		glog.Infof("[PAM] Failed to authenticate user (%s): %v", p.username, err)
 		return false
 	}
 
-	glog.Infof("[%s] Authentication passed. user=%s ", rc.ID, p.username)
+	glog.Infof("[PAM] Authentication passed user (%s)", p.username)
 	return true
 }
 
+// Checks with the PAM service that the account of a user logged in with a key is
+// usable, not locked nor expired
+func CheckPAMAccount(username string, remoteAddress string) error {
+
+	if err := PAMLibrary.CheckAccount(PAMService, username, remoteAddress); err != nil {
+		glog.Infof("[PAM] Account of user (%s) refused: %v", username, err)
+		return err
+	}
+
+	return nil
+}
+
 func (p PAMAuthenticator) Authorize(cmd string, cmdArgs string) bool {
 	return true
 }
 
 func (p PAMAuthenticator) Account(cmd string, cmdArgs string) bool {
 	return true
-}
\ No newline at end of file
+}
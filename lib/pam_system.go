@@ -0,0 +1,100 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"errors"
+
+	"github.com/golang/glog"
+	"github.com/msteinert/pam"
+)
+
+// systemPAM runs the PAM transactions with the system PAM library
+type systemPAM struct{}
+
+/*Authenticates the user and checks its account, as sshd does for a password login.
+The password answers the prompts without echo, the user name the prompts with echo
+*/
+func (systemPAM) Authenticate(service string, username string, password string, remoteAddress string) error {
+
+	transaction, err := startPAM(service, username, remoteAddress, func(style pam.Style) (string, error) {
+		switch style {
+		case pam.PromptEchoOff:
+			return password, nil
+		case pam.PromptEchoOn:
+			return username, nil
+		}
+		return "", errors.New("Unsupported PAM message style")
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := transaction.Authenticate(pam.DisallowNullAuthtok); err != nil {
+		return err
+	}
+
+	return transaction.AcctMgmt(pam.Silent)
+}
+
+// Checks the account of a user authenticated without PAM, as sshd does for a key
+// login, so that locked or expired accounts are refused
+func (systemPAM) CheckAccount(service string, username string, remoteAddress string) error {
+
+	transaction, err := startPAM(service, username, remoteAddress, func(style pam.Style) (string, error) {
+		return "", errors.New("Unsupported PAM prompt without password")
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return transaction.AcctMgmt(pam.Silent)
+}
+
+// startPAM starts a transaction of the user from remoteAddress, answer replies to
+// the prompts and the messages are logged
+func startPAM(service string, username string, remoteAddress string, answer func(style pam.Style) (string, error)) (*pam.Transaction, error) {
+
+	transaction, err := pam.StartFunc(service, username, func(style pam.Style, message string) (string, error) {
+		switch style {
+		case pam.ErrorMsg:
+			glog.Warningf("[PAM] %s", message)
+			return "", nil
+		case pam.TextInfo:
+			glog.Infof("[PAM] %s", message)
+			return "", nil
+		}
+		return answer(style)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if remoteAddress != "" {
+		if err := transaction.SetItem(pam.Rhost, remoteAddress); err != nil {
+			return nil, err
+		}
+	}
+
+	return transaction, nil
+}
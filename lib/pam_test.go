@@ -0,0 +1,84 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func init(){
+	fmt.Println("+++++ init pam_test +++++")
+}
+
+// fakePAM accepts the passwords of users, refuses the accounts of the locked
+// ones and records the transactions
+type fakePAM struct {
+	users        map[string]string
+	locked       map[string]bool
+	transactions []string
+}
+
+func (f *fakePAM) Authenticate(service string, username string, password string, remoteAddress string) error {
+	f.transactions = append(f.transactions, service+" "+username+" "+remoteAddress)
+	if expected, ok := f.users[username]; !ok || password != expected {
+		return errors.New("Authentication failure")
+	}
+	return nil
+}
+
+func (f *fakePAM) CheckAccount(service string, username string, remoteAddress string) error {
+	f.transactions = append(f.transactions, service+" "+username+" "+remoteAddress)
+	if f.locked[username] {
+		return errors.New("Account locked")
+	}
+	return nil
+}
+
+func TestPAMAuthenticator(t *testing.T) {
+
+	saved, savedService := PAMLibrary, PAMService
+	defer func() { PAMLibrary, PAMService = saved, savedService }()
+
+	backend := &fakePAM{users: map[string]string{"admin": "YourPaSsWoRd"}}
+	PAMLibrary = backend
+	PAMService = "netconf"
+
+	tests := []struct {
+		username string
+		password string
+		correct  bool
+	}{
+		{"admin", "YourPaSsWoRd", true},
+		{"admin", "wrong", false},
+		{"unknown", "YourPaSsWoRd", false},
+	}
+
+	for _, test := range tests {
+		if result := NewPAMAuthenticator(test.username, test.password, "10.0.0.1").Authenticate(); result != test.correct {
+			t.Errorf("Result was incorrect for %s/%s, got: %t, want: %t.", test.username, test.password, result, test.correct)
+		}
+	}
+
+	if result := fmt.Sprint(backend.transactions); result != "[netconf admin 10.0.0.1 netconf admin 10.0.0.1 netconf unknown 10.0.0.1]" {
+		t.Errorf("Result was incorrect, got: %s, want: a netconf transaction per login.", result)
+	}
+}
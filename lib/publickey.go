@@ -0,0 +1,382 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"orange/sonic-netconf-server/tacplus"
+
+	"github.com/golang/glog"
+	"golang.org/x/crypto/ssh"
+)
+
+// Public key login methods, the method of the authenticators of the key logins
+const (
+	LoginPublicKey   = "publickey"
+	LoginCertificate = "certificate"
+)
+
+// Authorized keys file of the users, relative to their home directory
+var AuthorizedKeysFile = ".ssh/authorized_keys"
+
+// File of the CA keys trusted to sign user certificates, as the sshd
+// TrustedUserCAKeys, no certificate is accepted when not set
+var TrustedUserCAKeys = ""
+
+// File mapping the certificate principals to users, a "<principal> <user>" line
+// per mapping. A principal matching the user name is always accepted
+var PrincipalsFile = ""
+
+// lookupHome returns the home directory of a user
+var lookupHome = func(username string) (string, error) {
+	u, err := user.Lookup(username)
+	if err != nil {
+		return "", err
+	}
+	return u.HomeDir, nil
+}
+
+/*Checks a public key of a user against its authorized keys file, or a certificate against the trusted user CA keys.
+Returns the login method, publickey or certificate, and an error when the key is not accepted
+*/
+func CheckPublicKey(username string, key ssh.PublicKey, remoteAddress string) (string, error) {
+
+	if cert, ok := key.(*ssh.Certificate); ok {
+		return LoginCertificate, checkCertificate(username, cert, remoteAddress)
+	}
+
+	return LoginPublicKey, checkAuthorizedKey(username, key, remoteAddress)
+}
+
+// Extensions of the permissions of a key login, the SSH handshake keeps the
+// permissions of the key whose signature it verified
+const (
+	extKeyFingerprint = "netconf-key-fingerprint"
+	extKeyMethod      = "netconf-key-method"
+)
+
+/*Public key callback of the SSH server, checks that a key offered by a client is accepted for the user.
+Nothing is set up here, the client may offer other keys and only prove to hold one of them.
+The fingerprint and login method of the key are recorded in its permissions, see VerifiedKey
+*/
+func AuthenticateKey(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+
+	method, err := CheckPublicKey(conn.User(), key, tacplus.RemoteIP(conn.RemoteAddr()))
+
+	if err != nil {
+		glog.Errorf("[AAA] Key authentication failed user:(%s): %v", conn.User(), err)
+		return nil, err
+	}
+
+	return &ssh.Permissions{Extensions: map[string]string{
+		extKeyFingerprint: ssh.FingerprintSHA256(key),
+		extKeyMethod:      method,
+	}}, nil
+}
+
+// Returns the fingerprint and login method of the key verified by the SSH
+// handshake of conn, ok is false for a login without key
+func VerifiedKey(conn *ssh.ServerConn) (fingerprint string, method string, ok bool) {
+
+	if conn == nil || conn.Permissions == nil || conn.Permissions.Extensions[extKeyFingerprint] == "" {
+		return "", "", false
+	}
+
+	return conn.Permissions.Extensions[extKeyFingerprint], conn.Permissions.Extensions[extKeyMethod], true
+}
+
+// checkAuthorizedKey looks for the key in the authorized keys of the user, the
+// from= option of the key restricting the client addresses
+func checkAuthorizedKey(username string, key ssh.PublicKey, remoteAddress string) error {
+
+	home, err := lookupHome(username)
+
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(home, AuthorizedKeysFile))
+
+	if err != nil {
+		return err
+	}
+
+	wire := key.Marshal()
+
+	for len(data) > 0 {
+
+		authorized, _, options, rest, err := ssh.ParseAuthorizedKey(data)
+
+		if err != nil {
+			break
+		}
+
+		data = rest
+
+		if !bytes.Equal(authorized.Marshal(), wire) {
+			continue
+		}
+
+		for _, option := range options {
+			name, value := splitOption(option)
+			switch name {
+			case "from":
+				if !matchAddressPatterns(value, remoteAddress) {
+					return fmt.Errorf("Key of user %s not authorized from %s", username, remoteAddress)
+				}
+			case "command", "cert-authority":
+				// A forced command would replace the NETCONF subsystem
+				return fmt.Errorf("Unsupported %s option of a key of user %s", name, username)
+			}
+		}
+
+		return nil
+	}
+
+	return errors.New("Key not authorized for user " + username)
+}
+
+// checkCertificate checks a user certificate signed by a trusted CA with a
+// principal of the user
+func checkCertificate(username string, cert *ssh.Certificate, remoteAddress string) error {
+
+	if cert.CertType != ssh.UserCert {
+		return errors.New("Not a user certificate")
+	}
+
+	if TrustedUserCAKeys == "" {
+		return errors.New("No trusted user CA keys configured")
+	}
+
+	authorities, err := readPublicKeys(TrustedUserCAKeys)
+
+	if err != nil {
+		return err
+	}
+
+	checker := ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			for _, authority := range authorities {
+				if bytes.Equal(authority.Marshal(), auth.Marshal()) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+
+	if !checker.IsUserAuthority(cert.SignatureKey) {
+		return errors.New("Certificate not signed by a trusted CA")
+	}
+
+	if sources, ok := cert.CriticalOptions["source-address"]; ok && !matchAddressPatterns(sources, remoteAddress) {
+		return fmt.Errorf("Certificate not valid from %s", remoteAddress)
+	}
+
+	principals, err := userPrincipals(username)
+
+	if err != nil {
+		return err
+	}
+
+	for _, principal := range cert.ValidPrincipals {
+		if contains(principals, principal) {
+			return checker.CheckCert(principal, cert)
+		}
+	}
+
+	return fmt.Errorf("No principal of the certificate maps to user %s", username)
+}
+
+// userPrincipals returns the certificate principals accepted for a user
+func userPrincipals(username string) ([]string, error) {
+
+	principals := []string{username}
+
+	if PrincipalsFile == "" {
+		return principals, nil
+	}
+
+	file, err := os.Open(PrincipalsFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && !strings.HasPrefix(fields[0], "#") && fields[1] == username {
+			principals = append(principals, fields[0])
+		}
+	}
+
+	return principals, scanner.Err()
+}
+
+// readPublicKeys reads a file of keys in the authorized keys format
+func readPublicKeys(path string) ([]ssh.PublicKey, error) {
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []ssh.PublicKey{}
+
+	for len(bytes.TrimSpace(data)) > 0 {
+		key, _, _, rest, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid key in %s: %v", path, err)
+		}
+		keys = append(keys, key)
+		data = rest
+	}
+
+	return keys, nil
+}
+
+// splitOption splits an authorized keys option such as from="10.0.0.0/8"
+func splitOption(option string) (string, string) {
+
+	i := strings.Index(option, "=")
+
+	if i < 0 {
+		return strings.ToLower(option), ""
+	}
+
+	return strings.ToLower(option[:i]), strings.Trim(option[i+1:], "\"")
+}
+
+/*Matches an address against a comma separated list of patterns, as sshd does for from= and source-address.
+A pattern is an address with the * and ? wildcards or a CIDR, a negated pattern prefixed by ! rejects the address
+*/
+func matchAddressPatterns(patterns string, address string) bool {
+
+	ip := net.ParseIP(address)
+	matched := false
+
+	for _, pattern := range strings.Split(patterns, ",") {
+
+		pattern = strings.TrimSpace(pattern)
+		negated := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+
+		if !matchAddressPattern(pattern, address, ip) {
+			continue
+		}
+
+		if negated {
+			return false
+		}
+
+		matched = true
+	}
+
+	return matched
+}
+
+func matchAddressPattern(pattern string, address string, ip net.IP) bool {
+
+	if strings.Contains(pattern, "/") {
+		_, network, err := net.ParseCIDR(pattern)
+		return err == nil && ip != nil && network.Contains(ip)
+	}
+
+	wildcard := regexp.QuoteMeta(pattern)
+	wildcard = strings.Replace(wildcard, `\*`, ".*", -1)
+	wildcard = strings.Replace(wildcard, `\?`, ".", -1)
+
+	matched, _ := regexp.MatchString("^"+wildcard+"$", address)
+
+	return matched
+}
+
+// KeyAuthenticator is the login authenticator of a public key or certificate
+// login, the key was checked during the SSH authentication
+type KeyAuthenticator struct {
+	username string
+	role     string
+}
+
+/*Returns the authenticator of a key login, once the account of the user is checked with the PAM service.
+The login gets the role of a password login of the user, admin unless the TACACS+ authorization gives another one.
+When the TACACS+ servers do not answer the login gets the read-only role, as a TACACS+ password login does
+*/
+func NewKeyAuthenticator(ctx context.Context, policy AAAPolicy, username string, remoteAddress string, port string) (Authenticator, error) {
+
+	if err := CheckPAMAccount(username, remoteAddress); err != nil {
+		return nil, errors.New("Account of user " + username + " not usable: " + err.Error())
+	}
+
+	if !contains(policy.Authorization, LoginTacacs) {
+		return KeyAuthenticator{username: username, role: RoleAdmin}, nil
+	}
+
+	tacacs, err := NewTacacsAuthenticator(ctx, "ssh", "shell", username, "", remoteAddress, port)
+
+	if err != nil {
+		glog.Warningf("[AAA] No TACACS+ server configured for the key login of user (%s): %v", username, err)
+		return KeyAuthenticator{username: username, role: RoleAdmin}, nil
+	}
+
+	tacacs, passed, err := tacacs.authorizeLogin()
+
+	if err != nil {
+		glog.Warningf("[AAA] Login authorization unavailable for user (%s), using role %s: %v", username, tacacs.Role(), err)
+	} else if !passed {
+		return nil, errors.New("Login of user " + username + " not authorized")
+	}
+
+	return KeyAuthenticator{username: username, role: tacacs.Role()}, nil
+}
+
+func (k KeyAuthenticator) Authenticate() bool {
+	return true
+}
+
+func (k KeyAuthenticator) Authorize(cmd string, cmdArgs string) bool {
+	return true
+}
+
+func (k KeyAuthenticator) Account(cmd string, cmdArgs string) bool {
+	return true
+}
+
+// Returns the role of the user
+func (k KeyAuthenticator) Role() string {
+	return k.role
+}
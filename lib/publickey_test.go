@@ -0,0 +1,269 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func init(){
+	fmt.Println("+++++ init publickey_test +++++")
+}
+
+func newTestKey(t *testing.T) (ssh.PublicKey, ssh.Signer) {
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := ssh.NewPublicKey(public)
+	signer, _ := ssh.NewSignerFromKey(private)
+
+	return key, signer
+}
+
+func newTestCert(t *testing.T, ca ssh.Signer, principals []string, options map[string]string) *ssh.Certificate {
+
+	key, _ := newTestKey(t)
+
+	cert := &ssh.Certificate{
+		Key:             key,
+		CertType:        ssh.UserCert,
+		ValidPrincipals: principals,
+		ValidAfter:      uint64(time.Now().Add(-time.Hour).Unix()),
+		ValidBefore:     uint64(time.Now().Add(time.Hour).Unix()),
+		Permissions:     ssh.Permissions{CriticalOptions: options},
+	}
+
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func TestCheckAuthorizedKey(t *testing.T) {
+
+	home, err := ioutil.TempDir("", "netconf-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	savedLookup := lookupHome
+	defer func() { lookupHome = savedLookup }()
+	lookupHome = func(username string) (string, error) { return home, nil }
+
+	anywhere, _ := newTestKey(t)
+	restricted, _ := newTestKey(t)
+	forced, _ := newTestKey(t)
+	unknown, _ := newTestKey(t)
+
+	authorizedKeys := "# automation\n" +
+		string(ssh.MarshalAuthorizedKey(anywhere)) +
+		"from=\"10.0.0.0/8,!10.0.0.66,192.168.1.?\" " + string(ssh.MarshalAuthorizedKey(restricted)) +
+		"command=\"/bin/true\" " + string(ssh.MarshalAuthorizedKey(forced))
+
+	os.MkdirAll(filepath.Join(home, ".ssh"), 0700)
+	if err := ioutil.WriteFile(filepath.Join(home, AuthorizedKeysFile), []byte(authorizedKeys), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key     ssh.PublicKey
+		address string
+		correct bool
+	}{
+		{anywhere, "172.16.0.1", true},
+		{restricted, "10.1.2.3", true},
+		{restricted, "192.168.1.7", true},
+		{restricted, "10.0.0.66", false},
+		{restricted, "172.16.0.1", false},
+		{forced, "10.1.2.3", false},
+		{unknown, "10.1.2.3", false},
+	}
+
+	for i, test := range tests {
+		method, err := CheckPublicKey("automation", test.key, test.address)
+		if (err == nil) != test.correct || method != LoginPublicKey {
+			t.Errorf("Result was incorrect for key %d from %s, got: %s (%v), want accepted: %t.", i, test.address, method, err, test.correct)
+		}
+	}
+}
+
+func TestCheckCertificate(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "netconf-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	savedCA, savedPrincipals := TrustedUserCAKeys, PrincipalsFile
+	defer func() { TrustedUserCAKeys, PrincipalsFile = savedCA, savedPrincipals }()
+
+	caKey, ca := newTestKey(t)
+	_, other := newTestKey(t)
+
+	TrustedUserCAKeys = filepath.Join(dir, "ca.pub")
+	PrincipalsFile = filepath.Join(dir, "principals")
+
+	ioutil.WriteFile(TrustedUserCAKeys, ssh.MarshalAuthorizedKey(caKey), 0600)
+	ioutil.WriteFile(PrincipalsFile, []byte("# principal user\nnetops-automation admin\n"), 0600)
+
+	tests := []struct {
+		cert    *ssh.Certificate
+		address string
+		correct bool
+	}{
+		{newTestCert(t, ca, []string{"admin"}, nil), "10.0.0.1", true},
+		{newTestCert(t, ca, []string{"netops-automation"}, nil), "10.0.0.1", true},
+		{newTestCert(t, ca, []string{"operator"}, nil), "10.0.0.1", false},
+		{newTestCert(t, other, []string{"admin"}, nil), "10.0.0.1", false},
+		{newTestCert(t, ca, []string{"admin"}, map[string]string{"source-address": "10.0.0.0/24"}), "10.0.0.1", true},
+		{newTestCert(t, ca, []string{"admin"}, map[string]string{"source-address": "10.0.0.0/24"}), "10.0.1.1", false},
+		{newTestCert(t, ca, []string{"admin"}, map[string]string{"force-command": "/bin/true"}), "10.0.0.1", false},
+	}
+
+	for i, test := range tests {
+		method, err := CheckPublicKey("admin", test.cert, test.address)
+		if (err == nil) != test.correct || method != LoginCertificate {
+			t.Errorf("Result was incorrect for certificate %d from %s, got: %s (%v), want accepted: %t.", i, test.address, method, err, test.correct)
+		}
+	}
+
+	TrustedUserCAKeys = ""
+
+	if _, err := CheckPublicKey("admin", tests[0].cert, "10.0.0.1"); err == nil {
+		t.Errorf("Result was incorrect, certificate accepted without trusted CA keys")
+	}
+}
+
+func TestKeyAuthenticatorRole(t *testing.T) {
+
+	saved := PAMLibrary
+	defer func() { PAMLibrary = saved }()
+
+	PAMLibrary = &fakePAM{locked: map[string]bool{"locked": true}}
+
+	authenticator, err := NewKeyAuthenticator(context.Background(), AAAPolicy{Authorization: []string{LoginLocal}}, "admin", "10.0.0.1", "netconf1")
+
+	if err != nil {
+		t.Fatalf("Result was incorrect, got: %v, want: no error.", err)
+	}
+
+	// The role of a local password login
+	if role := authenticator.(KeyAuthenticator).Role(); role != RoleAdmin {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", role, RoleAdmin)
+	}
+
+	if _, err := NewKeyAuthenticator(context.Background(), AAAPolicy{Authorization: []string{LoginLocal}}, "locked", "10.0.0.1", "netconf1"); err == nil {
+		t.Errorf("Result was incorrect, key login of a locked account accepted")
+	}
+}
+
+// forgedSigner offers a key without holding its private key, its signature
+// of another algorithm fails the attempt without ending the connection
+type forgedSigner struct {
+	ssh.Signer
+}
+
+func (s forgedSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	return &ssh.Signature{Format: ssh.KeyAlgoRSA, Blob: make([]byte, ed25519.SignatureSize)}, nil
+}
+
+func TestVerifiedKey(t *testing.T) {
+
+	home, err := ioutil.TempDir("", "netconf-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	savedLookup := lookupHome
+	defer func() { lookupHome = savedLookup }()
+	lookupHome = func(username string) (string, error) { return home, nil }
+
+	held, heldSigner := newTestKey(t)
+	other, otherSigner := newTestKey(t)
+	_, hostSigner := newTestKey(t)
+
+	// Both keys are authorized
+	os.MkdirAll(filepath.Join(home, ".ssh"), 0700)
+	if err := ioutil.WriteFile(filepath.Join(home, AuthorizedKeysFile), append(ssh.MarshalAuthorizedKey(held), ssh.MarshalAuthorizedKey(other)...), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	config := &ssh.ServerConfig{PublicKeyCallback: AuthenticateKey}
+	config.AddHostKey(hostSigner)
+
+	verified := make(chan *ssh.ServerConn, 1)
+
+	go func() {
+		nc, err := l.Accept()
+		if err != nil {
+			verified <- nil
+			return
+		}
+		conn, _, _, _ := ssh.NewServerConn(nc, config)
+		verified <- conn
+	}()
+
+	// The held key is accepted first, the other key is offered last and only
+	// the held key is signed
+	client, err := ssh.Dial("tcp", l.Addr().String(), &ssh.ClientConfig{
+		User:            "automation",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(forgedSigner{heldSigner}, forgedSigner{otherSigner}, heldSigner)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("Result was incorrect, got: %v, want: no error.", err)
+	}
+	defer client.Close()
+
+	fingerprint, method, ok := VerifiedKey(<-verified)
+
+	if want := ssh.FingerprintSHA256(held); !ok || fingerprint != want || method != LoginPublicKey {
+		t.Errorf("Result was incorrect, got: %s %s %t, want: %s %s.", fingerprint, method, ok, want, LoginPublicKey)
+	}
+
+	if _, _, ok := VerifiedKey(nil); ok {
+		t.Errorf("Result was incorrect, key found without connection")
+	}
+}
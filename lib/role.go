@@ -0,0 +1,93 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Roles of the NETCONF users
+const (
+	RoleAdmin    = "admin"     // Every operation
+	RoleReadOnly = "read-only" // Retrieval operations only
+)
+
+// Lowest TACACS+ privilege level of the admin role
+const AdminPrivLevel = 15
+
+// Returns the role of a TACACS+ privilege level
+func RoleForPrivLevel(level int) string {
+	if level >= AdminPrivLevel {
+		return RoleAdmin
+	}
+	return RoleReadOnly
+}
+
+/*Returns the role and privilege level of the attribute-value pairs of a TACACS+ authorization response.
+A role pair takes precedence over priv-lvl, unknown roles are read-only.
+found is false when the response has neither, or an out of range priv-lvl, the user then gets the read-only role
+*/
+func parseRole(args []string) (role string, privLvl int, found bool) {
+
+	roleValue := ""
+	privLvl = -1
+
+	for _, arg := range args {
+
+		// Mandatory pairs use '=', optional ones '*'
+		i := strings.IndexAny(arg, "=*")
+		if i < 0 {
+			continue
+		}
+
+		name := strings.TrimPrefix(strings.ToLower(arg[:i]), "shell:")
+		value := strings.TrimSpace(arg[i+1:])
+
+		switch name {
+		case "priv-lvl", "priv_lvl":
+			if level, err := strconv.Atoi(value); err == nil && level >= 0 && level <= AdminPrivLevel {
+				privLvl = level
+			}
+		case "role":
+			roleValue = strings.ToLower(value)
+		}
+	}
+
+	switch {
+	case roleValue == RoleAdmin:
+		role = RoleAdmin
+	case roleValue != "":
+		role = RoleReadOnly
+	case privLvl >= 0:
+		role = RoleForPrivLevel(privLvl)
+	default:
+		return RoleReadOnly, 1, false
+	}
+
+	if privLvl < 0 {
+		privLvl = AdminPrivLevel
+		if role == RoleReadOnly {
+			privLvl = 1
+		}
+	}
+
+	return role, privLvl, true
+}
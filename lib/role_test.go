@@ -0,0 +1,56 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+)
+
+func init(){
+	fmt.Println("+++++ init role_test +++++")
+}
+
+func TestParseRole(t *testing.T) {
+
+	tests := []struct {
+		args    []string
+		role    string
+		privLvl int
+		found   bool
+	}{
+		{[]string{"priv-lvl=15"}, RoleAdmin, 15, true},
+		{[]string{"priv-lvl=1"}, RoleReadOnly, 1, true},
+		{[]string{"shell:priv-lvl*7"}, RoleReadOnly, 7, true},
+		{[]string{"priv_lvl=15", "role=read-only"}, RoleReadOnly, 15, true},
+		{[]string{"role=admin", "priv-lvl=1"}, RoleAdmin, 1, true},
+		{[]string{"role=operator"}, RoleReadOnly, 1, true},
+		{[]string{"role=admin"}, RoleAdmin, 15, true},
+		{[]string{"priv-lvl=16", "timeout=5"}, RoleReadOnly, 1, false},
+		{nil, RoleReadOnly, 1, false},
+	}
+
+	for _, test := range tests {
+		role, privLvl, found := parseRole(test.args)
+		if role != test.role || privLvl != test.privLvl || found != test.found {
+			t.Errorf("Result was incorrect for %v, got: %s %d %t, want: %s %d %t.", test.args, role, privLvl, found, test.role, test.privLvl, test.found)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"orange/sonic-netconf-server/tacplus"
+
+	"github.com/golang/glog"
+)
+
+// aaaServer is the remote side of the command authorization and accounting
+type aaaServer interface {
+	authorize(cmd string, cmdArgs string) (bool, error)
+	account(flags uint8, args []string) error
+}
+
+// SessionAuthenticator authorizes and accounts the commands of a session with
+// the methods of the AAA configuration, whatever the login method was
+type SessionAuthenticator struct {
+	login    Authenticator
+	tacacs   aaaServer
+	policy   AAAPolicy
+	username string
+	taskID   string
+	start    time.Time
+}
+
+/*Creates the authenticator of a session logged in with login.
+A TACACS+ login is reused for authorization and accounting, other logins connect to a TACACS+ server when the policy uses one
+*/
+func NewSessionAuthenticator(ctx context.Context, login Authenticator, policy AAAPolicy, username string, remoteAddress string, port string) *SessionAuthenticator {
+
+	s := &SessionAuthenticator{
+		login:    login,
+		policy:   policy,
+		username: username,
+	}
+
+	if tacacs, ok := login.(TacacsAuthenticator); ok {
+		s.tacacs = tacacs
+	} else if contains(policy.Authorization, LoginTacacs) || contains(policy.Accounting, LoginTacacs) {
+		tacacs, err := NewTacacsAuthenticator(ctx, "ssh", "shell", username, "", remoteAddress, port)
+		if err != nil {
+			glog.Warningf("[AAA] No TACACS+ server available for user (%s): %v", username, err)
+		} else {
+			s.tacacs = tacacs
+		}
+	}
+
+	return s
+}
+
+func (s *SessionAuthenticator) Authenticate() bool {
+	return s.login.Authenticate()
+}
+
+// Returns the role of the user, admin unless the login method gave one
+func (s *SessionAuthenticator) Role() string {
+	if provider, ok := s.login.(interface{ Role() string }); ok {
+		return provider.Role()
+	}
+	return RoleAdmin
+}
+
+// Authorizes a command with the first authorization method able to answer,
+// local authorization allows every command of an authenticated user
+func (s *SessionAuthenticator) Authorize(cmd string, cmdArgs string) bool {
+
+	for _, method := range s.policy.Authorization {
+		switch method {
+		case LoginLocal:
+			return true
+		case LoginTacacs:
+			if s.tacacs == nil {
+				continue
+			}
+			allowed, err := s.tacacs.authorize(cmd, cmdArgs)
+			if err != nil {
+				glog.Warningf("[AAA] TACACS+ authorization unavailable for %s: %v", cmd, err)
+				continue
+			}
+			return allowed
+		default:
+			glog.Warningf("[AAA] Unsupported authorization method (%s), skipping", method)
+		}
+	}
+
+	glog.Errorf("[AAA] No authorization method available for %s, denied", cmd)
+
+	return false
+}
+
+// Records the start of a command under a new task_id, returned to record its end
+func (s *SessionAuthenticator) AccountStart(cmd string, cmdArgs string) (string, bool) {
+	taskID := newTaskID()
+	return taskID, s.account(tacplus.AcctFlagStart, "start", commandStartArgs(taskID, cmd, cmdArgs))
+}
+
+// Records the end of the command started under taskID, with the error it ended with
+func (s *SessionAuthenticator) AccountStop(taskID string, cmd string, cmdArgs string, errMsg string) bool {
+	return s.account(tacplus.AcctFlagStop, "stop", commandStopArgs(taskID, cmd, cmdArgs, errMsg))
+}
+
+// Records the end of a command
+func (s *SessionAuthenticator) Account(cmd string, cmdArgs string) bool {
+	return s.account(tacplus.AcctFlagStop, "stop", append([]string{"stop_time=" + unixTime()}, commandArgs(cmd, cmdArgs)...))
+}
+
+// Records the start of the session, under a new task_id
+func (s *SessionAuthenticator) SessionStart() bool {
+	s.taskID = newTaskID()
+	s.start = time.Now()
+	return s.account(tacplus.AcctFlagStart, "start", []string{"task_id=" + s.taskID, "start_time=" + unixTime()})
+}
+
+// Records that the session is still running
+func (s *SessionAuthenticator) SessionWatchdog() bool {
+	return s.account(tacplus.AcctFlagWatchdog, "watchdog", []string{"task_id=" + s.taskID, "elapsed_time=" + s.elapsedTime()})
+}
+
+// Records the end of the session and the reason it ended
+func (s *SessionAuthenticator) SessionStop(reason string) bool {
+	return s.account(tacplus.AcctFlagStop, "stop", []string{"task_id=" + s.taskID, "stop_time=" + unixTime(), "elapsed_time=" + s.elapsedTime(), "reason=" + reason})
+}
+
+// elapsedTime returns the seconds since the start of the session
+func (s *SessionAuthenticator) elapsedTime() string {
+	return strconv.FormatInt(int64(time.Since(s.start)/time.Second), 10)
+}
+
+// account sends a record to every accounting method, false when none of them
+// could record it
+func (s *SessionAuthenticator) account(flags uint8, record string, args []string) bool {
+
+	if len(s.policy.Accounting) == 0 {
+		return true
+	}
+
+	recorded := false
+
+	for _, method := range s.policy.Accounting {
+		switch method {
+		case LoginLocal:
+			glog.Infof("[AAA] Accounting %s user:(%s) %v", record, s.username, args)
+			recorded = true
+		case LoginTacacs:
+			if s.tacacs == nil {
+				continue
+			}
+			if err := s.tacacs.account(flags, args); err != nil {
+				glog.Warningf("[AAA] TACACS+ accounting %s failed: %v", record, err)
+				continue
+			}
+			recorded = true
+		default:
+			glog.Warningf("[AAA] Unsupported accounting method (%s), skipping", method)
+		}
+	}
+
+	return recorded
+}
+
+func unixTime() string {
+	return strconv.FormatInt(time.Now().Unix(), 10)
+}
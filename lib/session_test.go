@@ -0,0 +1,201 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"orange/sonic-netconf-server/tacplus"
+)
+
+func init(){
+	fmt.Println("+++++ init session_test +++++")
+}
+
+// testAAAServer answers the authorizations with allow unless down, and records
+// the accounting flags and arguments
+type testAAAServer struct {
+	allow   bool
+	down    bool
+	records []string
+}
+
+func (t *testAAAServer) authorize(cmd string, cmdArgs string) (bool, error) {
+	if t.down {
+		return false, errors.New("unreachable")
+	}
+	return t.allow, nil
+}
+
+func (t *testAAAServer) account(flags uint8, args []string) error {
+	if t.down {
+		return errors.New("unreachable")
+	}
+	t.records = append(t.records, fmt.Sprintf("%d:%s", flags, strings.Join(args, " ")))
+	return nil
+}
+
+func TestSessionAuthorize(t *testing.T) {
+
+	tests := []struct {
+		methods string
+		server  *testAAAServer
+		correct bool
+	}{
+		{"tacacs+", &testAAAServer{allow: true}, true},
+		{"tacacs+ local", &testAAAServer{allow: false}, false},
+		{"tacacs+ local", &testAAAServer{down: true}, true},
+		{"tacacs+", &testAAAServer{down: true}, false},
+		{"local", &testAAAServer{allow: false}, true},
+	}
+
+	for _, test := range tests {
+		s := &SessionAuthenticator{tacacs: test.server, policy: AAAPolicy{Authorization: ParseLoginMethods(test.methods)}}
+
+		if result := s.Authorize("get", "/openconfig-interfaces:interfaces"); result != test.correct {
+			t.Errorf("Result was incorrect for %s %+v, got: %t, want: %t.", test.methods, *test.server, result, test.correct)
+		}
+	}
+}
+
+func TestSessionAccount(t *testing.T) {
+
+	server := &testAAAServer{}
+	s := &SessionAuthenticator{tacacs: server, policy: AAAPolicy{Accounting: []string{LoginTacacs, LoginLocal}}}
+
+	taskID, _ := s.AccountStart("get", "/openconfig-interfaces:interfaces")
+	s.AccountStop(taskID, "get", "/openconfig-interfaces:interfaces", "")
+	other, _ := s.AccountStart("get-data", "")
+	s.AccountStop(other, "get-data", "", "Request canceled")
+
+	task := "task_id=" + taskID
+
+	if len(server.records) != 4 || other == taskID ||
+		!strings.HasPrefix(server.records[0], fmt.Sprintf("%d:%s start_time=", tacplus.AcctFlagStart, task)) ||
+		!strings.HasPrefix(server.records[1], fmt.Sprintf("%d:%s stop_time=", tacplus.AcctFlagStop, task)) ||
+		!strings.HasSuffix(server.records[1], "cmd=get cmd-arg=/openconfig-interfaces:interfaces") ||
+		!strings.Contains(server.records[3], "task_id="+other+" stop_time=") ||
+		!strings.HasSuffix(server.records[3], " err_msg=Request canceled cmd=get-data cmd-arg=") {
+		t.Errorf("Result was incorrect, got: %v, want: start and stop records of get and get-data paired by task_id.", server.records)
+	}
+
+	server.down = true
+
+	if !s.Account("get", "") {
+		t.Errorf("Result was incorrect, local accounting not recorded")
+	}
+
+	s.policy.Accounting = []string{LoginTacacs}
+
+	if s.Account("get", "") {
+		t.Errorf("Result was incorrect, accounting recorded with the server down")
+	}
+
+	s.policy.Accounting = nil
+
+	if !s.Account("get", "") {
+		t.Errorf("Result was incorrect, disabled accounting failed")
+	}
+}
+
+func TestSessionRecords(t *testing.T) {
+
+	server := &testAAAServer{}
+	s := &SessionAuthenticator{tacacs: server, policy: AAAPolicy{Accounting: []string{LoginTacacs}}}
+
+	s.SessionStart()
+	s.start = s.start.Add(-90 * time.Second)
+	s.SessionWatchdog()
+	s.SessionStop("close-session")
+
+	task := "task_id=" + s.taskID
+
+	if len(server.records) != 3 ||
+		!strings.HasPrefix(server.records[0], fmt.Sprintf("%d:%s start_time=", tacplus.AcctFlagStart, task)) ||
+		server.records[1] != fmt.Sprintf("%d:%s elapsed_time=90", tacplus.AcctFlagWatchdog, task) ||
+		!strings.HasPrefix(server.records[2], fmt.Sprintf("%d:%s stop_time=", tacplus.AcctFlagStop, task)) ||
+		!strings.HasSuffix(server.records[2], " elapsed_time=90 reason=close-session") {
+		t.Errorf("Result was incorrect, got: %v, want: start, watchdog and stop records of %s.", server.records, task)
+	}
+}
+
+// testAcctHandler records the flags of the accounting requests of a TACACS+ server
+type testAcctHandler struct {
+	sync.Mutex
+	flags []uint8
+}
+
+func (h *testAcctHandler) HandleAuthenStart(ctx context.Context, a *tacplus.AuthenStart, s *tacplus.ServerSession) *tacplus.AuthenReply {
+	return &tacplus.AuthenReply{Status: tacplus.AuthenStatusFail}
+}
+
+func (h *testAcctHandler) HandleAuthorRequest(ctx context.Context, a *tacplus.AuthorRequest, s *tacplus.ServerSession) *tacplus.AuthorResponse {
+	return &tacplus.AuthorResponse{Status: tacplus.AuthorStatusFail}
+}
+
+func (h *testAcctHandler) HandleAcctRequest(ctx context.Context, a *tacplus.AcctRequest, s *tacplus.ServerSession) *tacplus.AcctReply {
+	h.Lock()
+	h.flags = append(h.flags, a.Flags)
+	h.Unlock()
+	return &tacplus.AcctReply{Status: tacplus.AcctStatusSuccess}
+}
+
+func TestSessionStopAfterDisconnect(t *testing.T) {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	handler := &testAcctHandler{}
+	connHandler := &tacplus.ServerConnHandler{Handler: handler, ConnConfig: tacplus.ConnConfig{Secret: []byte("secret")}}
+	go (&tacplus.Server{ServeConn: connHandler.Serve}).Serve(l)
+
+	addr := l.Addr().(*net.TCPAddr)
+	info := tacplus.TacacsInfo{IP: addr.IP.String(), Port: addr.Port, Password: "secret", Timeout: 1}
+
+	// The login context is the one of the connection, canceled when it drops
+	ctx, cancel := context.WithCancel(context.Background())
+
+	tacacs := TacacsAuthenticator{client: tacplus.NewFailoverClient([]tacplus.TacacsInfo{info}), info: info, context: ctx, username: "admin", service: "shell"}
+	s := &SessionAuthenticator{tacacs: tacacs, policy: AAAPolicy{Accounting: []string{LoginTacacs}}}
+
+	s.SessionStart()
+	cancel()
+
+	if !s.SessionStop("transport-error") {
+		t.Errorf("Result was incorrect, stop record not sent after the disconnection")
+	}
+
+	handler.Lock()
+	defer handler.Unlock()
+
+	if len(handler.flags) != 2 || handler.flags[1] != tacplus.AcctFlagStop {
+		t.Errorf("Result was incorrect, got: %v, want: start and stop records.", handler.flags)
+	}
+}
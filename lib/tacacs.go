@@ -31,7 +31,7 @@ import (
 )
 
 type TacacsAuthenticator struct {
-	client        *tacplus.Client
+	client        *tacplus.FailoverClient
 	info          tacplus.TacacsInfo
 	context       context.Context
 	username      string
@@ -39,11 +39,16 @@ type TacacsAuthenticator struct {
 	protocol      string
 	service       string
 	remoteAddress string
+	port          string
 	authType      uint8
+	privLvl       uint8
+	role          string
 }
 
-// Will create a connection with the highest priority tacacs server
-func NewTacacsAuthenticator(context context.Context, protocol string, service string, username string, password string, remoteAddress string) (TacacsAuthenticator, error) {
+/*Will create a connection with the highest priority tacacs server.
+remoteAddress, the IP address of the client, is sent as rem_addr and port, the identifier of the session, as port
+*/
+func NewTacacsAuthenticator(context context.Context, protocol string, service string, username string, password string, remoteAddress string, port string) (TacacsAuthenticator, error) {
 
 	client, info, err := tacplus.CreateClient(context)
 
@@ -62,6 +67,8 @@ func NewTacacsAuthenticator(context context.Context, protocol string, service st
 		authenticatorInfo.authType = tacplus.AuthenTypeCHAP
 	case "mschap":
 		authenticatorInfo.authType = tacplus.AuthenTypeMSCHAP
+	case "mschapv2":
+		authenticatorInfo.authType = tacplus.AuthenTypeMSCHAPv2
 	default:
 		return TacacsAuthenticator{}, errors.New("Unkown authentication type")
 	}
@@ -74,27 +81,43 @@ func NewTacacsAuthenticator(context context.Context, protocol string, service st
 	authenticatorInfo.protocol = protocol
 	authenticatorInfo.service = service
 	authenticatorInfo.remoteAddress = remoteAddress
+	authenticatorInfo.port = port
+	authenticatorInfo.privLvl = AdminPrivLevel
+	authenticatorInfo.role = RoleAdmin
 
 	return authenticatorInfo, nil
 }
 
 func (t TacacsAuthenticator) Authenticate() bool {
+	passed, _ := t.authenticate()
+	return passed
+}
+
+// authenticate returns an error when the server could not give an answer,
+// false when the credentials were rejected
+func (t TacacsAuthenticator) authenticate() (bool, error) {
+
+	data, err := tacplus.AuthenData(t.authType, t.username, t.password)
+
+	if err != nil {
+		return false, err
+	}
 
 	authenReq := &tacplus.AuthenStart{
 		Action:        tacplus.AuthenActionLogin,
 		AuthenType:    t.authType,
 		AuthenService: tacplus.AuthenServicePPP,
 		PrivLvl:       0,
-		Port:          t.protocol,
+		Port:          t.port,
 		User:          t.username,
-		Data:          []byte(t.password),
+		Data:          data,
 		RemAddr:       t.remoteAddress,
 	}
 
 	authenRep, session, err := t.client.SendAuthenStart(t.context, authenReq)
 
 	if err != nil {
-		return false
+		return false, err
 	}
 
 	if authenRep.Status == tacplus.AuthenStatusGetPass {
@@ -106,106 +129,218 @@ func (t TacacsAuthenticator) Authenticate() bool {
 		}
 
 		if err != nil {
-			return false
+			return false, err
 		}
 
 	}
 
+	if authenRep.Status == tacplus.AuthenStatusError {
+		return false, errors.New("TACACS+ server error: " + authenRep.ServerMsg)
+	}
+
 	if authenRep.Status != tacplus.AuthenStatusPass{
 		glog.Infof("Success packet not received, authentication failed")
-		return false
+		return false, nil
 	}
 
-	
-	// if err != nil || authenRep.Status != tacplus.AuthenStatusPass {
-	// 	return false
-	// }
+	return true, nil
+}
 
-	// glog.Infof("Session from authentication %+v", session)
+// Returns the role of the user, given by the server when the login was authorized
+func (t TacacsAuthenticator) Role() string {
+	return t.role
+}
 
-	// authenRep, err = session.Continue(t.context, t.password)
+/*Authorizes the login shell of the user, as sshd does after a TACACS+ authentication.
+Returns the authenticator with the role and privilege level of the attribute-value pairs of the response,
+false when the server denies the login and an error, with the read-only role, when it could not give an answer
+*/
+func (t TacacsAuthenticator) authorizeLogin() (TacacsAuthenticator, bool, error) {
 
-	return true
+	authorReq := &tacplus.AuthorRequest{
+		AuthenMethod:  tacplus.AuthenMethodTACACSPlus,
+		PrivLvl:       0,
+		AuthenType:    t.authType,
+		AuthenService: tacplus.AuthenServicePPP,
+		User:          t.username,
+		Port:          t.port,
+		Arg:           []string{"service=" + t.service, "protocol=" + t.protocol, "cmd*"},
+		RemAddr:       t.remoteAddress,
+	}
+
+	authorReply, err := t.client.SendAuthorRequest(t.context, authorReq)
+
+	// Without an answer the user only gets the read-only role
+	if err != nil {
+		t.role = RoleReadOnly
+		t.privLvl = 1
+		return t, false, err
+	}
+
+	if authorReply.Status != tacplus.AuthorStatusPassAdd && authorReply.Status != tacplus.AuthorStatusPassRepl {
+		return t, false, nil
+	}
+
+	role, privLvl, found := parseRole(authorReply.Arg)
+
+	if !found {
+		glog.Infof("No privilege level for user (%s), using role %s", t.username, role)
+	}
+
+	t.role = role
+	t.privLvl = uint8(privLvl)
+
+	return t, true, nil
 }
 
 func (t TacacsAuthenticator) Authorize(cmd string, cmdArgs string) bool {
+	allowed, _ := t.authorize(cmd, cmdArgs)
+	return allowed
+}
+
+// authorize returns an error when the server could not give an answer, false
+// when the command is denied
+func (t TacacsAuthenticator) authorize(cmd string, cmdArgs string) (bool, error) {
 
 	authorArgs := []string{}
 	authorArgs = append(authorArgs, "protocol="+t.protocol)
 	authorArgs = append(authorArgs, "service="+t.service)
 	authorArgs = append(authorArgs, "timeout="+strconv.Itoa(t.info.Timeout))
-
-	cmd = "cmd=" + cmd
-	if len(cmd) >= 255 {
-		cmd = cmd[:251] + "..."
-	}
-
-	cmdArgs = "cmd-arg=" + cmdArgs
-	if len(cmdArgs) >= 255 {
-		cmdArgs = cmdArgs[:251] + "..."
-	}
-
-	authorArgs = append(authorArgs, cmd)
-	authorArgs = append(authorArgs, cmdArgs)
+	authorArgs = append(authorArgs, commandArgs(cmd, cmdArgs)...)
 
 	authorReq := &tacplus.AuthorRequest{
 		AuthenMethod:  tacplus.AuthenMethodTACACSPlus,
-		PrivLvl:       0,
+		PrivLvl:       t.privLvl,
 		AuthenType:    t.authType,
 		AuthenService: tacplus.AuthenServicePPP,
 		User:          t.username,
+		Port:          t.port,
 		Arg:           authorArgs,
 		RemAddr:       t.remoteAddress,
 	}
 
 	authorReply, err := t.client.SendAuthorRequest(t.context, authorReq)
 
-	if err != nil || authorReply.Status != tacplus.AuthorStatusPassAdd {
-		return false
+	if err != nil {
+		return false, err
 	}
 
-	return true
+	switch authorReply.Status {
+	case tacplus.AuthorStatusPassAdd, tacplus.AuthorStatusPassRepl:
+		return true, nil
+	case tacplus.AuthorStatusError:
+		return false, errors.New("TACACS+ server error: " + authorReply.ServerMsg)
+	}
+
+	return false, nil
 }
 
+// Records the end of a command
 func (t TacacsAuthenticator) Account(cmd string, cmdArgs string) bool {
+	return t.account(tacplus.AcctFlagStop, append([]string{"stop_time=" + unixTime()}, commandArgs(cmd, cmdArgs)...)) == nil
+}
 
-	acctArgs := []string{}
-	acctArgs = append(acctArgs, "start_time="+strconv.FormatInt(time.Now().Unix(), 10))
-	acctArgs = append(acctArgs, "service="+t.service)
+// Records the start of a command under a new task_id, returned to record its end
+func (t TacacsAuthenticator) AccountStart(cmd string, cmdArgs string) (string, bool) {
+	taskID := newTaskID()
+	return taskID, t.account(tacplus.AcctFlagStart, commandStartArgs(taskID, cmd, cmdArgs)) == nil
+}
 
-	cmd = "cmd=" + cmd
-	if len(cmd) >= 255 {
-		cmd = cmd[:254]
+// Records the end of the command started under taskID, with the error it ended with
+func (t TacacsAuthenticator) AccountStop(taskID string, cmd string, cmdArgs string, errMsg string) bool {
+	return t.account(tacplus.AcctFlagStop, commandStopArgs(taskID, cmd, cmdArgs, errMsg)) == nil
+}
+
+// Time to send an accounting record when the servers have no timeout
+var AccountingTimeout = 5 * time.Second
+
+// accountingContext returns the context of the accounting records. It is not the
+// context of the connection, canceled when the connection drops, so that the stop
+// record of a dropped session is still sent. It ends after the timeouts of all
+// the servers.
+func (t TacacsAuthenticator) accountingContext() (context.Context, context.CancelFunc) {
+
+	timeout := time.Duration(0)
+	for _, server := range t.client.Servers() {
+		timeout += time.Duration(server.Timeout) * time.Second
 	}
 
-	cmdArgs = "cmd-arg=" + cmdArgs
-	if len(cmdArgs) >= 255 {
-		cmdArgs = cmdArgs[:251] + "..."
+	if timeout == 0 {
+		timeout = AccountingTimeout
 	}
 
-	acctArgs = append(acctArgs, cmd)
-	acctArgs = append(acctArgs, cmdArgs)
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// account sends an accounting record with the service of the session
+func (t TacacsAuthenticator) account(flags uint8, args []string) error {
+
+	acctArgs := []string{"service=" + t.service}
+	acctArgs = append(acctArgs, args...)
 
 	acctReq := &tacplus.AcctRequest{
-		Flags:         tacplus.AcctFlagStart,
+		Flags:         flags,
 		AuthenMethod:  tacplus.AuthenMethodTACACSPlus,
-		PrivLvl:       0,
+		PrivLvl:       t.privLvl,
 		AuthenType:    t.authType,
 		AuthenService: tacplus.AuthenServicePPP,
 		User:          t.username,
+		Port:          t.port,
 		Arg:           acctArgs,
 		RemAddr:       t.remoteAddress,
 	}
 
-	acctRep, err := t.client.SendAcctRequest(t.context, acctReq)
+	ctx, cancel := t.accountingContext()
+	defer cancel()
+
+	acctRep, err := t.client.SendAcctRequest(ctx, acctReq)
+
+	if err != nil {
+		return err
+	}
+
+	if acctRep.Status != tacplus.AcctStatusSuccess {
+		return errors.New("TACACS+ accounting failed: " + acctRep.ServerMsg)
+	}
+
+	return nil
+}
+
+// commandArgs returns the cmd and cmd-arg attributes of a command, truncated to
+// the 255 bytes of an argument
+func commandArgs(cmd string, cmdArgs string) []string {
+	return []string{truncateArg("cmd=" + cmd), truncateArg("cmd-arg=" + cmdArgs)}
+}
 
-	if err != nil || acctRep.Status != tacplus.AcctStatusSuccess {
-		return false
+// commandStartArgs returns the attributes of the start record of a command,
+// RFC 8907 section 8.3
+func commandStartArgs(taskID string, cmd string, cmdArgs string) []string {
+	return append([]string{"task_id=" + taskID, "start_time=" + unixTime()}, commandArgs(cmd, cmdArgs)...)
+}
+
+// commandStopArgs returns the attributes of the stop record of a command, with
+// the task_id of its start record and the error it ended with when not empty
+func commandStopArgs(taskID string, cmd string, cmdArgs string, errMsg string) []string {
+
+	args := []string{"task_id=" + taskID, "stop_time=" + unixTime()}
+
+	if errMsg != "" {
+		args = append(args, truncateArg("err_msg="+errMsg))
 	}
 
-	return true
+	return append(args, commandArgs(cmd, cmdArgs)...)
 }
 
-func (t TacacsAuthenticator) Disconnect() {
-	t.client.Close()
+// truncateArg truncates an attribute-value pair to the 255 bytes of an argument
+func truncateArg(arg string) string {
+	if len(arg) >= 255 {
+		return arg[:251] + "..."
+	}
+	return arg
+}
+
+// newTaskID returns the task_id pairing the start and stop accounting records
+func newTaskID() string {
+	return strconv.Itoa(tacplus.GenerateRandomInt())
 }
+
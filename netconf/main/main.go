@@ -21,10 +21,15 @@ import (
 	"errors"
 	"flag"
 	"io/ioutil"
+	"net"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
+	"orange/sonic-netconf-server/lib"
 	"orange/sonic-netconf-server/netconf/server"
+	"orange/sonic-netconf-server/tacplus"
 
 	gliderssh "github.com/gliderlabs/ssh"
 	"github.com/golang/glog"
@@ -35,15 +40,53 @@ import (
 
 // Command line parameters
 var (
-	port             int    // Server port
-	clientAuth       string // Client auth mode
-	publicKeyPath    = "/etc/sonic/netconf-key.pub"
-	privateKeyPath   = "/etc/sonic/netconf-key"
+	port            int           // Server port
+	clientAuth      string        // Client auth mode
+	schemaPort      int           // Schema download port
+	schemaURL       string        // Schema download base URL advertised in the yang library
+	schemaTLSCert   string        // Schema download TLS certificate
+	schemaTLSKey    string        // Schema download TLS private key
+	tailfActions    bool          // Accept tail-f style actions
+	maxResponseSize int64         // Maximum rpc-reply size
+	getWorkers      int           // Concurrent translib requests per get
+	rpcTimeout      time.Duration // Maximum rpc processing time
+	sessionRPCs     int           // Concurrent read-only rpcs per session
+	acctWatchdog    time.Duration // Session accounting watchdog interval
+	masterKeyPath   string        // TACACS+ passkey master key
+	maxMessageSize  int64         // Maximum received message size
+	maxXMLDepth     int           // Maximum received element nesting
+	maxXMLAttrs     int           // Maximum attributes per received element
+	pamService      string        // PAM service of the local logins
+	authorizedKeys  string        // Authorized keys file of the users
+	trustedCAKeys   string        // CA keys of the user certificates
+	principalsFile  string        // Certificate principals to users mapping
+	tacacsStats     time.Duration // TACACS+ connection counters log interval
+	publicKeyPath   = "/etc/sonic/netconf-key.pub"
+	privateKeyPath  = "/etc/sonic/netconf-key"
 )
 
 func init() {
 	// Parse command line
 	flag.IntVar(&port, "port", 830, "Listen port")
+	flag.IntVar(&schemaPort, "schema_port", 0, "Schema download listen port, 0 disables the schema download endpoint")
+	flag.StringVar(&schemaURL, "schema_url", "", "Schema download base URL advertised in the yang library, defaults to http(s)://<hostname>:<schema_port>/yang")
+	flag.StringVar(&schemaTLSCert, "schema_tls_cert", "", "Schema download TLS certificate file")
+	flag.StringVar(&schemaTLSKey, "schema_tls_key", "", "Schema download TLS private key file")
+	flag.BoolVar(&tailfActions, "tailf_actions", false, "Accept and advertise tail-f style actions ("+server.CapTailfActions+")")
+	flag.Int64Var(&maxResponseSize, "max_response_size", 0, "Maximum rpc-reply size in bytes, larger replies return a too-big error. 0 for unlimited")
+	flag.IntVar(&getWorkers, "get_workers", 4, "Maximum number of translib requests of a get run concurrently")
+	flag.DurationVar(&rpcTimeout, "rpc_timeout", 0, "Maximum processing time of an rpc, e.g. 30s. 0 for no limit")
+	flag.IntVar(&sessionRPCs, "session_concurrency", 4, "Maximum number of read-only rpcs of a session processed concurrently")
+	flag.StringVar(&masterKeyPath, "tacacs_master_key", tacplus.MasterKeyPath, "Master key file of the encrypted TACACS+ passkeys")
+	flag.DurationVar(&acctWatchdog, "accounting_watchdog", 0, "Interval of the TACACS+ accounting watchdog records of a session, e.g. 10m. 0 for none")
+	flag.Int64Var(&maxMessageSize, "max_message_size", 32*1024*1024, "Maximum received message size in bytes, larger messages return a too-big error. 0 for unlimited")
+	flag.IntVar(&maxXMLDepth, "max_xml_depth", 256, "Maximum nesting depth of the elements of a received message. 0 for unlimited")
+	flag.IntVar(&maxXMLAttrs, "max_xml_attributes", 64, "Maximum number of attributes of an element of a received message. 0 for unlimited")
+	flag.StringVar(&pamService, "pam_service", lib.PAMService, "PAM service the local logins are authenticated with, it must only check the local accounts")
+	flag.StringVar(&authorizedKeys, "authorized_keys_file", lib.AuthorizedKeysFile, "Authorized keys file of the users, relative to their home directory")
+	flag.StringVar(&trustedCAKeys, "trusted_user_ca_keys", "", "File of the CA keys trusted to sign user certificates, certificates are not accepted when not set")
+	flag.StringVar(&principalsFile, "principals_file", "", "File mapping certificate principals to users, a \"<principal> <user>\" line per mapping")
+	flag.DurationVar(&tacacsStats, "tacacs_stats_interval", 0, "Interval of the log of the TACACS+ connection counters, e.g. 1h. 0 for none")
 	// flag.StringVar(&clientAuth, "client_auth", "none", "Client auth mode - none|user")
 	flag.Parse()
 	// Suppress warning messages related to logging before flag parse
@@ -61,31 +104,141 @@ func main() {
 	srv.SetOption(gliderssh.HostKeyFile(privateKeyPath))
 	srv.SetOption(gliderssh.NoPty())
 	srv.SetOption(gliderssh.PasswordAuth(authenticate))
+	srv.ServerConfigCallback = serverConfig
+
+	srv.SubsystemHandlers["netconf"] = sessionHandler
+
+	server.TailfActionsEnabled = tailfActions
+	server.MaxResponseSize = maxResponseSize
+	server.GetWorkers = getWorkers
+	server.RPCTimeout = rpcTimeout
+	server.SessionConcurrency = sessionRPCs
+	server.AccountingWatchdog = acctWatchdog
+	tacplus.MasterKeyPath = masterKeyPath
+	server.MaxMessageSize = maxMessageSize
+	server.MaxXMLDepth = maxXMLDepth
+	server.MaxXMLAttributes = maxXMLAttrs
+	lib.PAMService = pamService
+	lib.AuthorizedKeysFile = authorizedKeys
+	lib.TrustedUserCAKeys = trustedCAKeys
+	lib.PrincipalsFile = principalsFile
+
+	if tacacsStats > 0 {
+		go tacplus.LogPoolStats(tacacsStats, nil)
+	}
+
+	if schemaPort != 0 {
+		startSchemaServer()
+	}
 
-	srv.SubsystemHandlers["netconf"] = server.SessionHandler
 	srv.ListenAndServe()
 }
 
+func startSchemaServer() {
+
+	server.SchemaBaseURL = schemaURL
+
+	if server.SchemaBaseURL == "" {
+		scheme := "http"
+		if schemaTLSCert != "" && schemaTLSKey != "" {
+			scheme = "https"
+		}
+		hostname, _ := os.Hostname()
+		server.SchemaBaseURL = scheme + "://" + net.JoinHostPort(hostname, strconv.Itoa(schemaPort)) + strings.TrimSuffix(server.SchemaPathPrefix, "/")
+	}
+
+	go func() {
+		err := server.ServeSchemas(":"+strconv.Itoa(schemaPort), schemaTLSCert, schemaTLSKey)
+		glog.Errorf("Schema download endpoint stopped: %v", err)
+	}()
+}
+
 func authenticate(ctx gliderssh.Context, password string) bool {
 
-	pamAuthenticator := server.NewPAMAuthenticator(ctx.User(), password)
+	remoteAddress := tacplus.RemoteIP(ctx.RemoteAddr())
+	port := sessionPort(ctx)
+
+	authenticator, method, err := lib.Login(ctx, lib.GetAAAAuthentication(), ctx.User(), password, remoteAddress, port)
 
-	if !pamAuthenticator.Authenticate() {
-		glog.Errorf("[PAM] Authentication failed user:(%s)", ctx.User())
+	if err != nil {
+		glog.Errorf("[AAA] Authentication failed user:(%s)", ctx.User())
 		return false
 	}
 
-	ctx.SetValue("auth-type", "local")
+	startSession(ctx, authenticator, method, lib.GetAAAPolicy(), remoteAddress, port)
 
-	ctx.SetValue("auth", pamAuthenticator)
+	return true
+}
 
-	ctx.SetValue("uuid", uuid.New().String())
+// serverConfig checks the public keys with lib.AuthenticateKey: the gliderlabs
+// handler records the last key offered in the context, which is not always the
+// key the client proved to hold
+func serverConfig(ctx gliderssh.Context) *cryptossh.ServerConfig {
+	return &cryptossh.ServerConfig{PublicKeyCallback: lib.AuthenticateKey}
+}
+
+// sessionHandler runs a NETCONF session, setting up first the session of a key login
+func sessionHandler(s gliderssh.Session) {
+
+	if _, ok := s.Context().Value("auth").(lib.Authenticator); !ok && !startKeySession(s.Context()) {
+		s.Exit(1)
+		return
+	}
+
+	server.SessionHandler(s)
+}
+
+// startKeySession authorizes the login of the key verified by the SSH handshake
+// and records the authenticator of its session
+func startKeySession(ctx gliderssh.Context) bool {
+
+	conn, _ := ctx.Value(gliderssh.ContextKeyConn).(*cryptossh.ServerConn)
+	fingerprint, method, ok := lib.VerifiedKey(conn)
+
+	if !ok {
+		glog.Errorf("[AAA] No verified key for user:(%s)", ctx.User())
+		return false
+	}
+
+	remoteAddress := tacplus.RemoteIP(ctx.RemoteAddr())
+	port := sessionPort(ctx)
+	policy := lib.GetAAAPolicy()
+
+	authenticator, err := lib.NewKeyAuthenticator(ctx, policy, ctx.User(), remoteAddress, port)
+
+	if err != nil {
+		glog.Errorf("[AAA] Key authentication failed user:(%s) key:(%s): %v", ctx.User(), fingerprint, err)
+		return false
+	}
 
-	glog.Infof("Authentication success user:(%s)", ctx.User())
+	startSession(ctx, authenticator, method, policy, remoteAddress, port)
 
 	return true
 }
 
+// sessionPort returns the TACACS+ port of the session, the session-id is known
+// before the login so that the TACACS+ packets identify the session by it
+func sessionPort(ctx gliderssh.Context) string {
+	sessionID, ok := ctx.Value("session-id").(uint32)
+	if !ok {
+		sessionID = server.NewSessionID()
+		ctx.SetValue("session-id", sessionID)
+	}
+	return "netconf" + strconv.FormatUint(uint64(sessionID), 10)
+}
+
+// startSession records the authenticator of the session of a logged in user
+func startSession(ctx gliderssh.Context, authenticator lib.Authenticator, method string, policy lib.AAAPolicy, remoteAddress string, port string) {
+
+	ctx.SetValue("auth-type", method)
+
+	ctx.SetValue("auth", lib.NewSessionAuthenticator(ctx, authenticator, policy, ctx.User(), remoteAddress, port))
+
+	ctx.SetValue("uuid", uuid.New().String())
+
+	glog.Infof("Authentication success user:(%s) method:(%s)", ctx.User(), method)
+}
+
 func MakeSSHKeyPair(pubKeyPath, privateKeyPath string) error {
 
 	if fileExists(publicKeyPath) && fileExists(privateKeyPath) {
@@ -15,7 +15,6 @@ package server
 
 import (
 	"github.com/golang/glog"
-	"golang.org/x/crypto/ssh"
 )
 
 type Authenticator interface {
@@ -24,50 +23,72 @@ type Authenticator interface {
 	Account(cmd string, cmdArgs string) bool
 }
 
+// Roles of the NETCONF users
+const (
+	RoleAdmin    = "admin"
+	RoleReadOnly = "read-only"
+)
 
-type PAMAuthenticator struct {
-	username string
-	password string
+// RoleProvider is implemented by the authenticators knowing the role of the user
+type RoleProvider interface {
+	Role() string
 }
 
-func NewPAMAuthenticator(username string, password string) PAMAuthenticator {
-	return PAMAuthenticator{
-		username: username,
-		password: password,
+// userRole returns the role of the user of an authenticator, admin when unknown
+func userRole(authenticator Authenticator) string {
+	if provider, ok := authenticator.(RoleProvider); ok {
+		return provider.Role()
 	}
+	return RoleAdmin
 }
 
-func (p PAMAuthenticator) Authenticate() bool {
-	var rc struct{
-		ID string
-	}
+// CommandAccounter is implemented by the authenticators recording the start of
+// the commands. The stop record carries the task_id of the start record.
+type CommandAccounter interface {
+	AccountStart(cmd string, cmdArgs string) (string, bool)
+	AccountStop(taskID string, cmd string, cmdArgs string, errMsg string) bool
+}
 
-	rc.ID = p.username
+// SessionAccounter is implemented by the authenticators recording the start,
+// watchdog updates and end of the NETCONF sessions
+type SessionAccounter interface {
+	SessionStart() bool
+	SessionWatchdog() bool
+	SessionStop(reason string) bool
+}
 
-	glog.Infof("[%s] Received user=%s", rc.ID, p.username)
+// accountCommand records the start of a command when the authenticator supports
+// it, and returns the function recording its end with the error it ended with.
+// An accounting failure is only logged, the command is not undone.
+func accountCommand(authenticator Authenticator, cmd string, cmdArgs string) func(err error) {
 
-	//Use ssh for authentication.
-	config := &ssh.ClientConfig{
-		User: p.username,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(p.password),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-	}
-	_, err := ssh.Dial("tcp", "127.0.0.1:22", config)
-	if err != nil {
-		glog.Infof("[%s] Failed to authenticate; %v", rc.ID, err)
-		return false
+	accounter, ok := authenticator.(CommandAccounter)
+
+	if !ok {
+		return func(err error) {
+			if !authenticator.Account(cmd, cmdArgs) {
+				glog.Warningf("[AUTH] Accounting failed %s - args:%s", cmd, cmdArgs)
+				return
+			}
+			glog.Infof("[AUTH] Accounting passed - %s: %s", cmd, cmdArgs)
+		}
 	}
 
-	glog.Infof("[%s] Authentication passed. user=%s ", rc.ID, p.username)
-	return true
-}
+	taskID, started := accounter.AccountStart(cmd, cmdArgs)
 
-func (p PAMAuthenticator) Authorize(cmd string, cmdArgs string) bool {
-	return true
-}
+	if !started {
+		glog.Warningf("[AUTH] Accounting start failed %s - args:%s", cmd, cmdArgs)
+	}
 
-func (p PAMAuthenticator) Account(cmd string, cmdArgs string) bool {
-	return true
-}
\ No newline at end of file
+	return func(err error) {
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+		}
+		if !accounter.AccountStop(taskID, cmd, cmdArgs, errMsg) {
+			glog.Warningf("[AUTH] Accounting failed %s - args:%s", cmd, cmdArgs)
+			return
+		}
+		glog.Infof("[AUTH] Accounting passed - %s: %s", cmd, cmdArgs)
+	}
+}
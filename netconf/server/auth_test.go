@@ -14,7 +14,10 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"testing"
 )
 
 func init(){
@@ -41,5 +44,88 @@ func (p TestAuthenticator) Account(cmd string, cmdArgs string) bool {
 	return p.shouldPass
 }
 
+// TestCommandAccounter records the start and stop records of the commands
+type TestCommandAccounter struct {
+	TestAuthenticator
+	records []string
+}
+
+func (p *TestCommandAccounter) AccountStart(cmd string, cmdArgs string) (string, bool) {
+	taskID := fmt.Sprint(len(p.records) + 1)
+	p.records = append(p.records, "start "+taskID+" "+cmd)
+	return taskID, true
+}
+
+func (p *TestCommandAccounter) AccountStop(taskID string, cmd string, cmdArgs string, errMsg string) bool {
+	p.records = append(p.records, "stop "+taskID+" "+cmd+" "+errMsg)
+	return false
+}
+
+func TestAccountCommand(t *testing.T) {
+	withTestSchemaTree(t, func() {
 
+		accounter := &TestCommandAccounter{TestAuthenticator: NewTestAuthenticator(true)}
+
+		// Failed after the start record
+		op := parseTestOperation(t, "<clear-counters xmlns=\""+nsTestOps+"\"><unknown>1</unknown></clear-counters>")
+		_, err := RPCRequestHandler(context.Background(), accounter, op)
+		checkRPCErrorTag(t, err, ErrTagUnknownElement)
+
+		// A failed stop record does not fail the applied action
+		op = parseTestOperation(t, "<clear-counters xmlns=\""+nsTestOps+"\"/>")
+		if _, err = RPCRequestHandler(context.Background(), accounter, op); err != nil {
+			t.Errorf("Result was incorrect, got: %v, want the action result.", err)
+		}
+
+		result := strings.Join(accounter.records, ",")
+		correct := "start 1 clear-counters,stop 1 clear-counters Unknown element unknown,start 3 clear-counters,stop 3 clear-counters "
+
+		if result != correct {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+		}
+	})
+}
 
+// TestRoleAuthenticator is a TestAuthenticator with a role
+type TestRoleAuthenticator struct {
+	TestAuthenticator
+	role string
+}
+
+func (p TestRoleAuthenticator) Role() string {
+	return p.role
+}
+
+func TestReadOnlyRole(t *testing.T) {
+
+	tests := []struct {
+		role    string
+		request string
+		denied  bool
+	}{
+		{RoleReadOnly, "<kill-session><session-id>4</session-id></kill-session>", true},
+		{RoleReadOnly, "<edit-config><target><running/></target></edit-config>", true},
+		{RoleReadOnly, "<commit/>", true},
+		{RoleReadOnly, "<close-session/>", false},
+		{RoleAdmin, "<kill-session><session-id>4</session-id></kill-session>", false},
+		{RoleReadOnly, "", false},
+	}
+
+	for _, test := range tests {
+		request := SessionRequest{
+			xml:           "<rpc message-id=\"1\" xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\">" + test.request + "</rpc>",
+			authenticator: TestRoleAuthenticator{NewTestAuthenticator(true), test.role},
+		}
+
+		result := processReply(request)
+		reply := result.String()
+
+		if denied := strings.Contains(reply, "<error-tag>access-denied</error-tag>"); denied != test.denied {
+			t.Errorf("Result was incorrect for %s %s, got: %s, want denied: %t.", test.role, test.request, reply, test.denied)
+		}
+
+		if test.request == "" && !strings.Contains(reply, "<error-tag>missing-element</error-tag>") {
+			t.Errorf("Result was incorrect, got: %s, want a missing-element error for an rpc without operation.", reply)
+		}
+	}
+}
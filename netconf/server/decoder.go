@@ -0,0 +1,352 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+// xmlToJSON converts the child elements of element, instances of the children
+// of schema, to the RFC 7951 JSON members expected by translib
+func xmlToJSON(element *xmlquery.Node, schema *schemaNode) (map[string]interface{}, error) {
+	return decodeMembers(element, schema, schemaPath(schema))
+}
+
+func decodeMembers(element *xmlquery.Node, schema *schemaNode, path string) (map[string]interface{}, error) {
+
+	members := map[string]interface{}{}
+
+	for child := element.FirstChild; child != nil; child = child.NextSibling {
+
+		if child.Type != xmlquery.ElementNode {
+			continue
+		}
+
+		node := schema.child(child.Data, child.NamespaceURI)
+
+		if node == nil || node.kind == schemaRPC || node.kind == schemaAction {
+			return nil, NewRPCError(ErrTypeApplication, ErrTagUnknownElement, "Unknown element %s", child.Data).
+				WithBadElement(child.Data).WithPath(path + "/" + child.Data).withPathNamespaces(schema)
+		}
+
+		name := node.name
+		if node.module != schema.module {
+			name = node.module + ":" + name
+		}
+
+		childPath := path + "/" + modulePrefix(node.module) + ":" + node.name
+
+		switch node.kind {
+		case schemaContainer, schemaInput, schemaOutput:
+			if _, ok := members[name]; ok {
+				return nil, duplicateElement(child, node, childPath)
+			}
+			value, err := decodeMembers(child, node, childPath)
+			if err != nil {
+				return nil, err
+			}
+			members[name] = value
+		case schemaList:
+			entry, err := decodeListEntry(child, node, childPath)
+			if err != nil {
+				return nil, err
+			}
+			list, _ := members[name].([]interface{})
+			members[name] = append(list, entry)
+		case schemaLeafList:
+			value, err := decodeLeaf(child, node, childPath)
+			if err != nil {
+				return nil, err
+			}
+			list, _ := members[name].([]interface{})
+			members[name] = append(list, value)
+		case schemaAnydata:
+			members[name] = anydataValue(child)
+		default:
+			if _, ok := members[name]; ok {
+				return nil, duplicateElement(child, node, childPath)
+			}
+			value, err := decodeLeaf(child, node, childPath)
+			if err != nil {
+				return nil, err
+			}
+			members[name] = value
+		}
+	}
+
+	return members, nil
+}
+
+func decodeListEntry(element *xmlquery.Node, schema *schemaNode, path string) (map[string]interface{}, error) {
+
+	predicates := ""
+
+	for _, key := range schema.keys {
+		keyNode := xmlquery.FindOne(element, "./*[local-name() = '"+key+"']")
+		if keyNode == nil {
+			return nil, NewRPCError(ErrTypeProtocol, ErrTagMissingElement, "Missing key %s of list %s", key, schema.name).
+				WithBadElement(key).WithPath(path).withPathNamespaces(schema)
+		}
+		predicates += "[" + modulePrefix(schema.module) + ":" + key + "=" + xpathLiteral(strings.TrimSpace(keyNode.InnerText())) + "]"
+	}
+
+	return decodeMembers(element, schema, path+predicates)
+}
+
+// xpathLiteral quotes a key value for an error-path predicate. XPath 1.0 literals
+// have no escape, a value with both quotes is built with concat().
+func xpathLiteral(s string) string {
+
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+
+	if !strings.Contains(s, "\"") {
+		return "\"" + s + "\""
+	}
+
+	return "concat('" + strings.Replace(s, "'", "', \"'\", '", -1) + "')"
+}
+
+func duplicateElement(element *xmlquery.Node, schema *schemaNode, path string) *RPCError {
+	return NewRPCError(ErrTypeApplication, ErrTagBadElement, "Element %s found more than once", element.Data).
+		WithBadElement(element.Data).WithPath(path).withPathNamespaces(schema)
+}
+
+// withPathNamespaces declares the namespaces of the modules of schema and its
+// ancestors, the prefixes of an error-path built by the decoder
+func (e *RPCError) withPathNamespaces(schema *schemaNode) *RPCError {
+	for n := schema; n != nil && n != schemaRoot; n = n.parent {
+		if n.namespace != "" {
+			e.WithPathNamespace(modulePrefix(n.module), n.namespace)
+		}
+	}
+	return e
+}
+
+// anydataValue returns the content of an anydata element, its child elements
+// serialized as XML
+func anydataValue(element *xmlquery.Node) string {
+
+	var b strings.Builder
+
+	for child := element.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(child.OutputXML(true))
+	}
+
+	return b.String()
+}
+
+// decodeLeaf returns the JSON value of a leaf or leaf-list element, RFC 7951 section 6
+func decodeLeaf(element *xmlquery.Node, schema *schemaNode, path string) (interface{}, error) {
+
+	if xmlquery.FindOne(element, "./*") != nil {
+		return nil, NewRPCError(ErrTypeApplication, ErrTagBadElement, "Unexpected child elements in %s", element.Data).
+			WithBadElement(element.Data).WithPath(path).withPathNamespaces(schema)
+	}
+
+	value, ok := leafValue(element, element.InnerText(), schema.typ, schema.module)
+
+	if !ok {
+		return nil, NewRPCError(ErrTypeApplication, ErrTagBadElement, "Invalid value %q for %s", element.InnerText(), element.Data).
+			WithBadElement(element.Data).WithPath(path).withPathNamespaces(schema)
+	}
+
+	return value, nil
+}
+
+// leafValue converts the XML text of a leaf to its JSON value, ok is false when
+// text is not a valid value of typ
+func leafValue(element *xmlquery.Node, text string, typ *schemaType, module string) (interface{}, bool) {
+
+	if typ == nil {
+		return text, true
+	}
+
+	trimmed := strings.TrimSpace(text)
+
+	switch typ.kind {
+	case yang.Yint8:
+		return intValue(trimmed, 8)
+	case yang.Yint16:
+		return intValue(trimmed, 16)
+	case yang.Yint32:
+		return intValue(trimmed, 32)
+	case yang.Yuint8:
+		return uintValue(trimmed, 8)
+	case yang.Yuint16:
+		return uintValue(trimmed, 16)
+	case yang.Yuint32:
+		return uintValue(trimmed, 32)
+	case yang.Yint64:
+		// 64 bit numbers are strings in JSON
+		_, err := strconv.ParseInt(trimmed, 10, 64)
+		return trimmed, err == nil
+	case yang.Yuint64:
+		_, err := strconv.ParseUint(trimmed, 10, 64)
+		return trimmed, err == nil
+	case yang.Ydecimal64:
+		return decimalValue(trimmed, typ.fractionDigits)
+	case yang.Ybool:
+		if trimmed != "true" && trimmed != "false" {
+			return nil, false
+		}
+		return trimmed == "true", true
+	case yang.Yempty:
+		if trimmed != "" {
+			return nil, false
+		}
+		return []interface{}{nil}, true
+	case yang.Yidentityref:
+		return identityrefValue(element, trimmed, module)
+	case yang.Yenum, yang.Ybits, yang.YinstanceIdentifier:
+		return trimmed, true
+	case yang.Yunion:
+		// The first member type accepting the value wins, RFC 7950 section 9.12
+		for _, member := range typ.union {
+			if member.kind == yang.Ystring {
+				return text, true
+			}
+			if value, ok := leafValue(element, text, member, module); ok {
+				return value, true
+			}
+		}
+		return nil, false
+	}
+
+	return text, true
+}
+
+func intValue(text string, bits int) (interface{}, bool) {
+	if _, err := strconv.ParseInt(text, 10, bits); err != nil {
+		return nil, false
+	}
+	return json.Number(text), true
+}
+
+func uintValue(text string, bits int) (interface{}, bool) {
+	if _, err := strconv.ParseUint(text, 10, bits); err != nil {
+		return nil, false
+	}
+	return json.Number(text), true
+}
+
+// decimalValue checks a decimal64 value against its fraction digits, the JSON
+// value is a string
+func decimalValue(text string, fractionDigits int) (interface{}, bool) {
+
+	sign, digits := "", text
+	if strings.HasPrefix(text, "-") {
+		sign, digits = "-", text[1:]
+	}
+
+	integer, fraction := digits, ""
+
+	if i := strings.Index(digits, "."); i >= 0 {
+		integer, fraction = digits[:i], digits[i+1:]
+		if fraction == "" {
+			return nil, false
+		}
+	}
+
+	if integer == "" || len(fraction) > fractionDigits {
+		return nil, false
+	}
+
+	// The value scaled by the fraction digits must fit in 64 bits, RFC 7950 section 9.3
+	scaled, ok := new(big.Int).SetString(sign+integer+fraction+strings.Repeat("0", fractionDigits-len(fraction)), 10)
+
+	if !ok || !scaled.IsInt64() {
+		return nil, false
+	}
+
+	return text, true
+}
+
+// identityrefValue translates an XML prefixed identity, resolved with the
+// namespace declarations in scope of element, to the module qualified JSON form
+func identityrefValue(element *xmlquery.Node, text string, module string) (interface{}, bool) {
+
+	if text == "" {
+		return nil, false
+	}
+
+	prefix, name := "", text
+
+	if i := strings.Index(text, ":"); i >= 0 {
+		prefix, name = text[:i], text[i+1:]
+	}
+
+	// An unprefixed identity is in the default namespace in scope,
+	// RFC 7950 section 9.10.3
+	namespace := lookupNamespace(element, prefix)
+
+	if namespace == "" && prefix == "" {
+		// No namespace in scope, identity of the module of the leaf
+		return module + ":" + name, true
+	}
+
+	identityModule, ok := schemaNamespaces[namespace]
+
+	if !ok {
+		return nil, false
+	}
+
+	return identityModule + ":" + name, true
+}
+
+// lookupNamespace returns the namespace bound to prefix in the scope of element,
+// the default namespace when prefix is empty
+func lookupNamespace(element *xmlquery.Node, prefix string) string {
+	for n := element; n != nil; n = n.Parent {
+		for _, attr := range n.Attr {
+			if prefix == "" && attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+				return attr.Value
+			}
+			if prefix != "" && attr.Name.Space == "xmlns" && attr.Name.Local == prefix {
+				return attr.Value
+			}
+		}
+	}
+	return ""
+}
+
+// modulePrefix returns the prefix of module, used in error paths
+func modulePrefix(module string) string {
+	if file := findYangFile(module, ""); file != nil && file.prefix != "" {
+		return file.prefix
+	}
+	return module
+}
+
+// schemaPath returns the schema node path of node, input and output excluded
+func schemaPath(node *schemaNode) string {
+
+	path := ""
+
+	for n := node; n != nil && n != schemaRoot; n = n.parent {
+		if n.kind == schemaInput || n.kind == schemaOutput {
+			continue
+		}
+		path = "/" + modulePrefix(n.module) + ":" + n.name + path
+	}
+
+	return path
+}
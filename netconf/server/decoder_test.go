@@ -0,0 +1,175 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+func init(){
+	fmt.Println("+++++ init decoder_test +++++")
+}
+
+func decodeTestData(t *testing.T, dataXML string) (string, error) {
+	doc, err := xmlquery.Parse(strings.NewReader("<config xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\">" + dataXML + "</config>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	members, err := xmlToJSON(xmlquery.FindOne(doc, "/*"), schemaRoot)
+	if err != nil {
+		return "", err
+	}
+	result, _ := json.Marshal(members)
+	return string(result), nil
+}
+
+func TestDecodeData(t *testing.T) {
+	withTestAugment(t, func() {
+
+		result, err := decodeTestData(t, "<interfaces xmlns=\""+nsTestOps+"\"><interface><name> Ethernet0 </name><mtu> 9100 </mtu><enabled>false</enabled>"+
+			"<speed xmlns=\""+nsTestAug+"\" xmlns:x=\""+nsTestAug+"\">x:SPEED_10G</speed><tags xmlns=\""+nsTestAug+"\">a</tags><tags xmlns=\""+nsTestAug+"\">b</tags>"+
+			"<auto xmlns=\""+nsTestAug+"\"/></interface><interface xmlns:a=\""+nsTestAug+"\"><name>Ethernet4</name><a:speed>SPEED_1G</a:speed></interface></interfaces>")
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		correct := `{"test-ops:interfaces":{"interface":[{"enabled":false,"mtu":9100,"name":" Ethernet0 ","test-aug:auto":[null],"test-aug:speed":"test-aug:SPEED_10G","test-aug:tags":["a","b"]},{"name":"Ethernet4","test-aug:speed":"test-ops:SPEED_1G"}]}}`
+
+		if result != correct {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+		}
+	})
+}
+
+func TestDecodeDataErrors(t *testing.T) {
+	withTestAugment(t, func() {
+
+		tests := []struct {
+			data string
+			tag  string
+			path string
+		}{
+			{"<interfaces xmlns=\"" + nsTestOps + "\"><interface><name>Ethernet0</name><bogus/></interface></interfaces>", ErrTagUnknownElement,
+				"/test-ops:interfaces/test-ops:interface[test-ops:name='Ethernet0']/bogus"},
+			{"<interfaces xmlns=\"" + nsTestOps + "\"><interface><name>Ethernet0</name><mtu>70000</mtu></interface></interfaces>", ErrTagBadElement,
+				"/test-ops:interfaces/test-ops:interface[test-ops:name='Ethernet0']/test-ops:mtu"},
+			{"<interfaces xmlns=\"" + nsTestOps + "\"><interface><name>Ethernet0</name><enabled>yes</enabled></interface></interfaces>", ErrTagBadElement,
+				"/test-ops:interfaces/test-ops:interface[test-ops:name='Ethernet0']/test-ops:enabled"},
+			{"<interfaces xmlns=\"" + nsTestOps + "\"><interface><name>Ethernet0</name><speed xmlns=\"" + nsTestAug + "\">y:SPEED_10G</speed></interface></interfaces>", ErrTagBadElement,
+				"/test-ops:interfaces/test-ops:interface[test-ops:name='Ethernet0']/ta:speed"},
+			{"<interfaces xmlns=\"" + nsTestOps + "\"><interface><name>Eth'0</name><bogus/></interface></interfaces>", ErrTagUnknownElement,
+				"/test-ops:interfaces/test-ops:interface[test-ops:name=\"Eth'0\"]/bogus"},
+			{"<interfaces xmlns=\"" + nsTestOps + "\"><interface><name>Eth'\"0</name><bogus/></interface></interfaces>", ErrTagUnknownElement,
+				"/test-ops:interfaces/test-ops:interface[test-ops:name=concat('Eth', \"'\", '\"0')]/bogus"},
+			{"<interfaces xmlns=\"" + nsTestOps + "\"><interface><mtu>1500</mtu></interface></interfaces>", ErrTagMissingElement,
+				"/test-ops:interfaces/test-ops:interface"},
+			{"<interfaces xmlns=\"urn:unknown\"/>", ErrTagUnknownElement, "/interfaces"},
+		}
+
+		for _, test := range tests {
+			_, err := decodeTestData(t, test.data)
+			checkRPCErrorTag(t, err, test.tag)
+			var rpcErr *RPCError
+			if errors.As(err, &rpcErr) && rpcErr.ErrorPath != test.path {
+				t.Errorf("Result was incorrect, got: %s, want: %s.", rpcErr.ErrorPath, test.path)
+			}
+		}
+	})
+}
+
+func TestDecodeErrorPathNamespaces(t *testing.T) {
+	withTestAugment(t, func() {
+
+		_, err := decodeTestData(t, "<interfaces xmlns=\""+nsTestOps+"\"><interface><name>Ethernet0</name><speed xmlns=\""+nsTestAug+"\">y:SPEED_10G</speed></interface></interfaces>")
+
+		var rpcErr *RPCError
+		if !errors.As(err, &rpcErr) {
+			t.Fatalf("Result was incorrect, got: %v, want: an rpc-error.", err)
+		}
+
+		result := rpcErr.xml()
+		correct := "<error-path xmlns:ta=\"" + nsTestAug + "\" xmlns:test-ops=\"" + nsTestOps + "\">/test-ops:interfaces/test-ops:interface[test-ops:name=&apos;Ethernet0&apos;]/ta:speed</error-path>"
+
+		if !strings.Contains(result, correct) {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+		}
+	})
+}
+
+func TestDecodeAnydata(t *testing.T) {
+	withTestSchemaTree(t, func() {
+
+		interfaces := schemaRoot.child("interfaces", "")
+		extra := testSchemaNode("extra", schemaAnydata, 0)
+		extra.parent = interfaces
+		interfaces.children = append(interfaces.children, extra)
+
+		result, err := decodeTestData(t, "<interfaces xmlns=\""+nsTestOps+"\"><extra><vendor>acme</vendor><mode>fast</mode></extra></interfaces>")
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		var members map[string]map[string]string
+		json.Unmarshal([]byte(result), &members)
+
+		value := members["test-ops:interfaces"]["extra"]
+		for _, element := range []string{">acme</vendor>", ">fast</mode>"} {
+			if !strings.Contains(value, element) {
+				t.Errorf("Result was incorrect, got: %s, want the element %s.", value, element)
+			}
+		}
+	})
+}
+
+func TestLeafValue(t *testing.T) {
+
+	decimal := &schemaType{kind: yang.Ydecimal64, fractionDigits: 2}
+	union := &schemaType{kind: yang.Yunion, union: []*schemaType{{kind: yang.Yuint8}, {kind: yang.Yenum}}}
+
+	tests := []struct {
+		text  string
+		typ   *schemaType
+		value interface{}
+		ok    bool
+	}{
+		{"1.25", decimal, "1.25", true},
+		{"-0.5", decimal, "-0.5", true},
+		{"12", decimal, "12", true},
+		{"1.255", decimal, nil, false},
+		{"1.", decimal, nil, false},
+		{"92233720368547758.08", decimal, nil, false},
+		{"5", union, json.Number("5"), true},
+		{"auto", union, "auto", true},
+		{"-9000000000", &schemaType{kind: yang.Yint64}, "-9000000000", true},
+		{"-1", &schemaType{kind: yang.Yuint32}, nil, false},
+		{"", &schemaType{kind: yang.Yempty}, []interface{}{nil}, true},
+	}
+
+	for _, test := range tests {
+		value, ok := leafValue(nil, test.text, test.typ, "test-ops")
+		if ok != test.ok || !reflect.DeepEqual(value, test.value) {
+			t.Errorf("Result was incorrect for %q, got: %v %v, want: %v %v.", test.text, value, ok, test.value, test.ok)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+// pathSegment is a node of a translib path, /module:name[key=value]/...
+type pathSegment struct {
+	module string
+	name   string
+	keys   [][2]string
+}
+
+// parsePath splits a translib path into its segments, key values may contain
+// / and escaped ] or \
+func parsePath(path string) []pathSegment {
+
+	segments := []pathSegment{}
+	var current *pathSegment
+	var token strings.Builder
+	var key string
+	inKey, escaped := false, false
+
+	for _, c := range path {
+		switch {
+		case escaped:
+			token.WriteRune(c)
+			escaped = false
+		case inKey && c == '\\':
+			escaped = true
+		case inKey && c == '=' && key == "":
+			key = token.String()
+			token.Reset()
+		case inKey && c == ']':
+			current.keys = append(current.keys, [2]string{key, token.String()})
+			token.Reset()
+			key = ""
+			inKey = false
+		case inKey:
+			token.WriteRune(c)
+		case c == '[':
+			if current.name == "" {
+				current.name = token.String()
+				token.Reset()
+			}
+			inKey = true
+		case c == '/':
+			if current != nil && current.name == "" {
+				current.name = token.String()
+			}
+			token.Reset()
+			segments = append(segments, pathSegment{})
+			current = &segments[len(segments)-1]
+		case c == ':' && current != nil && current.module == "" && current.name == "":
+			current.module = token.String()
+			token.Reset()
+		default:
+			token.WriteRune(c)
+		}
+	}
+
+	if current != nil && current.name == "" {
+		current.name = token.String()
+	}
+
+	return segments
+}
+
+// resolvePath returns the schema node of every segment of path, nil for the
+// segments not found in the schema
+func resolvePath(segments []pathSegment) []*schemaNode {
+
+	nodes := make([]*schemaNode, len(segments))
+	parent := schemaRoot
+	module := ""
+
+	for i, segment := range segments {
+		if segment.module != "" {
+			module = segment.module
+		}
+		if parent != nil {
+			parent = parent.childInModule(segment.name, module)
+		}
+		nodes[i] = parent
+	}
+
+	return nodes
+}
+
+// childInModule returns the child node name defined by module, any module when empty
+func (n *schemaNode) childInModule(name string, module string) *schemaNode {
+	for _, child := range n.children {
+		if child.name == name && (module == "" || child.module == module) {
+			return child
+		}
+	}
+	return nil
+}
+
+// xmlEncoder writes translib RFC 7951 JSON as NETCONF XML following the schema:
+// namespaces on module changes, list keys first, leaf-lists as repeated
+// elements, empty leaves as <leaf/> and identityrefs with their prefix
+type xmlEncoder struct {
+	w       io.Writer
+	err     error
+	filters []string
+}
+
+func (e *xmlEncoder) write(s string) {
+	if e.err == nil {
+		_, e.err = io.WriteString(e.w, s)
+	}
+}
+
+// decodeJSON decodes a translib payload keeping numbers as they are
+func decodeJSON(payload []byte) (map[string]interface{}, error) {
+
+	var members map[string]interface{}
+
+	if len(bytes.TrimSpace(payload)) == 0 {
+		return members, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	err := decoder.Decode(&members)
+
+	return members, err
+}
+
+// encodeGetMembers writes the decoded translib get response of path as XML,
+// wrapped in the ancestors of the requested node. Only the leaves in filters are
+// kept in the entries of the requested list, when filters are set.
+func encodeGetMembers(w io.Writer, path string, members map[string]interface{}, filters []string) error {
+
+	if len(members) == 0 {
+		return nil
+	}
+
+	segments := parsePath(path)
+
+	if len(segments) == 0 {
+		return fmt.Errorf("Invalid path %s", path)
+	}
+
+	nodes := resolvePath(segments)
+	e := &xmlEncoder{w: w}
+	namespace := ""
+	module := ""
+
+	// Ancestors of the requested node
+	for i, segment := range segments[:len(segments)-1] {
+
+		if segment.module != "" {
+			module = segment.module
+		}
+
+		ns := segmentNamespace(nodes[i], module, namespace)
+
+		e.write("<" + segment.name)
+		if ns != namespace {
+			e.write(" xmlns=\"" + escapeXML(ns) + "\"")
+		}
+		e.write(">")
+
+		for _, key := range segment.keys {
+			e.write("<" + key[0] + ">" + escapeXML(key[1]) + "</" + key[0] + ">")
+		}
+
+		namespace = ns
+	}
+
+	var parent *schemaNode
+	if len(nodes) > 1 {
+		parent = nodes[len(nodes)-2]
+	} else {
+		parent = schemaRoot
+	}
+
+	last := segments[len(segments)-1]
+
+	if last.module != "" {
+		module = last.module
+	}
+
+	if parent == nil {
+		// Not in the schema, keep the namespace of the module
+		namespace = segmentNamespace(nil, module, "")
+	}
+
+	e.filters = filters
+	e.writeMembers(members, parent, module, namespace)
+
+	for i := len(segments) - 2; i >= 0; i-- {
+		e.write("</" + segments[i].name + ">")
+	}
+
+	return e.err
+}
+
+// segmentNamespace returns the namespace of a path segment not found in the
+// schema from the yang library
+func segmentNamespace(node *schemaNode, module string, namespace string) string {
+
+	if node != nil {
+		return node.namespace
+	}
+
+	yangSchemas, _ := yangLibrary()
+
+	if schemas, ok := yangSchemas[module]; ok && len(schemas) > 0 {
+		return schemas[0].NameSpace
+	}
+
+	return namespace
+}
+
+// writeMembers writes the JSON members of schema node parent in the order of
+// its children, keys first then statement order, the members unknown to the
+// schema last. module and namespace are the ones of parent.
+func (e *xmlEncoder) writeMembers(members map[string]interface{}, parent *schemaNode, module string, namespace string) {
+
+	type member struct {
+		name   string
+		module string
+		node   *schemaNode
+		value  interface{}
+	}
+
+	known := map[*schemaNode]member{}
+	unknown := []member{}
+
+	for name, value := range members {
+
+		m := member{name: name, module: module, value: value}
+
+		if i := strings.Index(name, ":"); i >= 0 {
+			m.module, m.name = name[:i], name[i+1:]
+		}
+
+		if parent != nil {
+			m.node = parent.childInModule(m.name, m.module)
+		}
+
+		if m.node != nil {
+			known[m.node] = m
+		} else {
+			unknown = append(unknown, m)
+		}
+	}
+
+	if parent != nil {
+		for _, child := range parent.children {
+			if m, ok := known[child]; ok {
+				e.writeMember(m.name, m.value, m.node, m.module, namespace)
+			}
+		}
+	}
+
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].name < unknown[j].name })
+
+	for _, m := range unknown {
+		e.writeMember(m.name, m.value, nil, m.module, segmentNamespace(nil, m.module, namespace))
+	}
+}
+
+func (e *xmlEncoder) writeMember(name string, value interface{}, node *schemaNode, module string, parentNamespace string) {
+
+	namespace := parentNamespace
+	if node != nil {
+		namespace = node.namespace
+	}
+
+	if list, ok := value.([]interface{}); ok && !isEmptyValue(value) {
+		filters := e.filters
+		if node != nil && node.kind == schemaList && len(filters) != 0 {
+			// Filters only apply to the requested list, not to the lists below it
+			e.filters = nil
+		}
+		for _, item := range list {
+			if entry, ok := item.(map[string]interface{}); ok && len(filters) != 0 {
+				item = filterMembers(entry, filters)
+			}
+			e.writeElement(name, item, node, module, namespace, parentNamespace)
+		}
+		e.filters = filters
+		return
+	}
+
+	e.writeElement(name, value, node, module, namespace, parentNamespace)
+}
+
+func filterMembers(entry map[string]interface{}, filters []string) map[string]interface{} {
+	filtered := map[string]interface{}{}
+	for name, value := range entry {
+		local := name
+		if i := strings.Index(name, ":"); i >= 0 {
+			local = name[i+1:]
+		}
+		if contains(filters, local) {
+			filtered[name] = value
+		}
+	}
+	return filtered
+}
+
+// isEmptyValue reports whether value is the [null] value of an empty leaf
+func isEmptyValue(value interface{}) bool {
+	list, ok := value.([]interface{})
+	return ok && len(list) == 1 && list[0] == nil
+}
+
+func (e *xmlEncoder) writeElement(name string, value interface{}, node *schemaNode, module string, namespace string, parentNamespace string) {
+
+	e.write("<" + name)
+
+	if namespace != parentNamespace {
+		e.write(" xmlns=\"" + escapeXML(namespace) + "\"")
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		e.write(">")
+		e.writeMembers(v, node, module, namespace)
+	case nil:
+		e.write("/>")
+		return
+	case []interface{}:
+		// [null] is the value of an empty leaf
+		e.write("/>")
+		return
+	case bool:
+		e.write(">" + strconv.FormatBool(v))
+	case json.Number:
+		e.write(">" + v.String())
+	case string:
+		if prefix, ns, ident, ok := identityValue(v, node); ok {
+			e.write(" xmlns:" + prefix + "=\"" + escapeXML(ns) + "\">" + prefix + ":" + escapeXML(ident))
+		} else {
+			e.write(">" + escapeXML(v))
+		}
+	default:
+		e.write(">" + escapeXML(fmt.Sprintf("%v", v)))
+	}
+
+	e.write("</" + name + ">")
+}
+
+// identityValue splits a module qualified identityref value, RFC 7951 section 6.8,
+// into the XML prefix and namespace of the module and the identity name
+func identityValue(value string, node *schemaNode) (string, string, string, bool) {
+
+	if node == nil || node.typ == nil || !node.typ.hasKind(yang.Yidentityref) {
+		return "", "", "", false
+	}
+
+	i := strings.Index(value, ":")
+
+	if i <= 0 {
+		return "", "", "", false
+	}
+
+	module := value[:i]
+	file := findYangFile(module, "")
+
+	if file == nil || file.namespace == "" {
+		return "", "", "", false
+	}
+
+	prefix := file.prefix
+	if prefix == "" {
+		prefix = module
+	}
+
+	return prefix, file.namespace, value[i+1:], true
+}
+
+// hasKind reports whether the type, or one of its union members, is of kind
+func (t *schemaType) hasKind(kind yang.TypeKind) bool {
+	if t.kind == kind {
+		return true
+	}
+	for _, member := range t.union {
+		if member.hasKind(kind) {
+			return true
+		}
+	}
+	return false
+}
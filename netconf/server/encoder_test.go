@@ -0,0 +1,129 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+func init(){
+	fmt.Println("+++++ init encoder_test +++++")
+}
+
+const nsTestAug = "http://example.com/test-aug"
+
+// withTestAugment adds leaves of module test-aug to the interface list of the test-ops tree
+func withTestAugment(t *testing.T, f func()) {
+	withTestSchemaTree(t, func() {
+
+		savedFiles := yangFiles
+		defer func() { yangFiles = savedFiles }()
+
+		schemaNamespaces[nsTestAug] = "test-aug"
+
+		yangFiles = map[string][]*yangFile{
+			"test-aug": {{name: "test-aug", namespace: nsTestAug, prefix: "ta"}},
+		}
+
+		iface := schemaRoot.child("interfaces", "").child("interface", "")
+
+		for _, node := range []*schemaNode{
+			testSchemaNode("speed", schemaLeaf, yang.Yidentityref),
+			testSchemaNode("tags", schemaLeafList, yang.Ystring),
+			testSchemaNode("auto", schemaLeaf, yang.Yempty),
+		} {
+			node.module, node.namespace, node.parent = "test-aug", nsTestAug, iface
+			iface.children = append(iface.children, node)
+		}
+
+		f()
+	})
+}
+
+func encodeTestResponse(t *testing.T, path string, payload string, filters []string) string {
+	members, err := decodeJSON([]byte(payload))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	var b strings.Builder
+	if err := encodeGetMembers(&b, path, members, filters); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	return b.String()
+}
+
+func TestParsePath(t *testing.T) {
+
+	result := parsePath("/test-ops:interfaces/interface[name=Ethernet/0][id=a\\]b]/test-aug:speed")
+
+	correct := []pathSegment{
+		{module: "test-ops", name: "interfaces"},
+		{name: "interface", keys: [][2]string{{"name", "Ethernet/0"}, {"id", "a]b"}}},
+		{module: "test-aug", name: "speed"},
+	}
+
+	if !reflect.DeepEqual(result, correct) {
+		t.Errorf("Result was incorrect, got: %+v, want: %+v.", result, correct)
+	}
+}
+
+func TestEncodeGetResponse(t *testing.T) {
+	withTestAugment(t, func() {
+
+		payload := `{"test-ops:interface":[{"test-aug:auto":[null],"enabled":true,"mtu":9100,"test-aug:tags":["a","b"],"test-aug:speed":"test-aug:SPEED_10G","name":"Ethernet<0>"}]}`
+
+		result := encodeTestResponse(t, "/test-ops:interfaces/interface[name=Ethernet<0>]", payload, nil)
+
+		correct := "<interfaces xmlns=\"" + nsTestOps + "\"><interface><name>Ethernet&lt;0&gt;</name><mtu>9100</mtu><enabled>true</enabled>" +
+			"<speed xmlns=\"" + nsTestAug + "\" xmlns:ta=\"" + nsTestAug + "\">ta:SPEED_10G</speed>" +
+			"<tags xmlns=\"" + nsTestAug + "\">a</tags><tags xmlns=\"" + nsTestAug + "\">b</tags>" +
+			"<auto xmlns=\"" + nsTestAug + "\"/></interface></interfaces>"
+
+		if result != correct {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+		}
+
+		result = encodeTestResponse(t, "/test-ops:interfaces/interface[name=Ethernet0]/mtu", `{"test-ops:mtu":1500}`, nil)
+		correct = "<interfaces xmlns=\"" + nsTestOps + "\"><interface><name>Ethernet0</name><mtu>1500</mtu></interface></interfaces>"
+
+		if result != correct {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+		}
+
+		result = encodeTestResponse(t, "/test-ops:interfaces", `{}`, nil)
+
+		if result != "" {
+			t.Errorf("Result was incorrect, got: %s, want empty result.", result)
+		}
+	})
+}
+
+func TestEncodeGetResponseFilters(t *testing.T) {
+	withTestAugment(t, func() {
+
+		payload := `{"test-ops:interface":[{"mtu":9100,"name":"Ethernet0"},{"mtu":1500,"name":"Ethernet4"}]}`
+
+		result := encodeTestResponse(t, "/test-ops:interfaces/interface", payload, []string{"name"})
+		correct := "<interfaces xmlns=\"" + nsTestOps + "\"><interface><name>Ethernet0</name></interface><interface><name>Ethernet4</name></interface></interfaces>"
+
+		if result != correct {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+		}
+	})
+}
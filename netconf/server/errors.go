@@ -0,0 +1,151 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Error types as defined in RFC 6241 section 4.3
+const (
+	ErrTypeTransport   = "transport"
+	ErrTypeRPC         = "rpc"
+	ErrTypeProtocol    = "protocol"
+	ErrTypeApplication = "application"
+)
+
+// Error tags as defined in RFC 6241 appendix A
+const (
+	ErrTagInUse                 = "in-use"
+	ErrTagInvalidValue          = "invalid-value"
+	ErrTagTooBig                = "too-big"
+	ErrTagMissingAttribute      = "missing-attribute"
+	ErrTagBadAttribute          = "bad-attribute"
+	ErrTagUnknownAttribute      = "unknown-attribute"
+	ErrTagMissingElement        = "missing-element"
+	ErrTagBadElement            = "bad-element"
+	ErrTagUnknownElement        = "unknown-element"
+	ErrTagUnknownNamespace      = "unknown-namespace"
+	ErrTagAccessDenied          = "access-denied"
+	ErrTagLockDenied            = "lock-denied"
+	ErrTagResourceDenied        = "resource-denied"
+	ErrTagRollbackFailed        = "rollback-failed"
+	ErrTagDataExists            = "data-exists"
+	ErrTagDataMissing           = "data-missing"
+	ErrTagOperationNotSupported = "operation-not-supported"
+	ErrTagOperationFailed       = "operation-failed"
+	ErrTagPartialOperation      = "partial-operation"
+	ErrTagMalformedMessage      = "malformed-message"
+)
+
+// NewRPCError creates an rpc-error with severity error
+func NewRPCError(errType string, errTag string, format string, args ...interface{}) *RPCError {
+	return &RPCError{
+		ErrorType:     errType,
+		ErrorTag:      errTag,
+		ErrorSeverity: "error",
+		ErrorMessage:  fmt.Sprintf(format, args...),
+	}
+}
+
+// WithAppTag sets the error-app-tag of the error
+func (e *RPCError) WithAppTag(appTag string) *RPCError {
+	e.ErrorAppTag = appTag
+	return e
+}
+
+// WithPath sets the error-path of the error
+func (e *RPCError) WithPath(path string) *RPCError {
+	e.ErrorPath = path
+	return e
+}
+
+// WithPathNamespace declares the namespace of a prefix used in the error-path
+func (e *RPCError) WithPathNamespace(prefix string, namespace string) *RPCError {
+	if e.PathNamespaces == nil {
+		e.PathNamespaces = map[string]string{}
+	}
+	e.PathNamespaces[prefix] = namespace
+	return e
+}
+
+// WithBadElement sets the bad-element error-info of the error
+func (e *RPCError) WithBadElement(element string) *RPCError {
+	if e.ErrorInfo == nil {
+		e.ErrorInfo = &RPCErrorInfo{}
+	}
+	e.ErrorInfo.BadElement = element
+	return e
+}
+
+func (e *RPCError) Error() string {
+	return e.ErrorMessage
+}
+
+// xml renders the rpc-error element
+func (e *RPCError) xml() string {
+
+	var b strings.Builder
+
+	b.WriteString("<rpc-error>")
+	b.WriteString("<error-type>" + e.ErrorType + "</error-type>")
+	b.WriteString("<error-tag>" + e.ErrorTag + "</error-tag>")
+	b.WriteString("<error-severity>" + e.ErrorSeverity + "</error-severity>")
+
+	if e.ErrorAppTag != "" {
+		b.WriteString("<error-app-tag>" + escapeXML(e.ErrorAppTag) + "</error-app-tag>")
+	}
+
+	if e.ErrorPath != "" {
+		prefixes := make([]string, 0, len(e.PathNamespaces))
+		for prefix := range e.PathNamespaces {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+
+		b.WriteString("<error-path")
+		for _, prefix := range prefixes {
+			b.WriteString(" xmlns:" + prefix + "=\"" + escapeXML(e.PathNamespaces[prefix]) + "\"")
+		}
+		b.WriteString(">" + escapeXML(e.ErrorPath) + "</error-path>")
+	}
+
+	if e.ErrorMessage != "" {
+		b.WriteString("<error-message xml:lang=\"en\">" + escapeXML(e.ErrorMessage) + "</error-message>")
+	}
+
+	if info := e.ErrorInfo; info != nil {
+		b.WriteString("<error-info>")
+		if info.BadElement != "" {
+			b.WriteString("<bad-element>" + escapeXML(info.BadElement) + "</bad-element>")
+		}
+		if info.BadAttribute != "" {
+			b.WriteString("<bad-attribute>" + escapeXML(info.BadAttribute) + "</bad-attribute>")
+		}
+		if info.BadNamespace != "" {
+			b.WriteString("<bad-namespace>" + escapeXML(info.BadNamespace) + "</bad-namespace>")
+		}
+		if info.SessionID != "" {
+			b.WriteString("<session-id>" + escapeXML(info.SessionID) + "</session-id>")
+		}
+		b.Write(info.InnerXML)
+		b.WriteString("</error-info>")
+	}
+
+	b.WriteString("</rpc-error>")
+
+	return b.String()
+}
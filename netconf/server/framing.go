@@ -0,0 +1,291 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// Maximum size of a received message in bytes, 0 for unlimited. Larger
+// messages are discarded and answered with a too-big error.
+var MaxMessageSize int64 = 32 * 1024 * 1024
+
+// Maximum nesting depth of the elements of a received message, 0 for unlimited
+var MaxXMLDepth = 256
+
+// Maximum number of attributes of an element of a received message, 0 for unlimited
+var MaxXMLAttributes = 64
+
+// Largest chunk-size allowed by RFC 6242 section 4.2
+const maxChunkSize = 4294967295
+
+// framingError is a malformed-message error after which the message boundaries
+// are lost and the session must be closed
+type framingError struct {
+	*RPCError
+}
+
+func (e framingError) Unwrap() error {
+	return e.RPCError
+}
+
+func newFramingError(format string, args ...interface{}) error {
+	return framingError{NewRPCError(ErrTypeRPC, ErrTagMalformedMessage, format, args...)}
+}
+
+// messageReader reads the messages of a session, framed by the end-of-message
+// delimiter of RFC 6242 section 4.3 or chunked as in section 4.2
+type messageReader struct {
+	r       *bufio.Reader
+	maxSize int64
+}
+
+func newMessageReader(r io.Reader) *messageReader {
+	return &messageReader{r: bufio.NewReader(r), maxSize: MaxMessageSize}
+}
+
+// readMessage returns the next message. A message larger than the maximum size
+// is skipped, its beginning is returned with a too-big error. io.EOF is returned
+// when the session ends between messages.
+func (m *messageReader) readMessage() (string, error) {
+
+	// Skip the white spaces between messages, a line feed followed by # starts
+	// a chunked message
+	for {
+		b, err := m.r.Peek(1)
+		if err != nil {
+			return "", err
+		}
+
+		if b[0] == '\n' {
+			if next, err := m.r.Peek(2); err == nil && next[1] == '#' {
+				return m.readChunked()
+			}
+		} else if b[0] != ' ' && b[0] != '\t' && b[0] != '\r' {
+			return m.readDelimited()
+		}
+
+		m.r.Discard(1)
+	}
+}
+
+// readDelimited reads a message up to the end-of-message delimiter
+func (m *messageReader) readDelimited() (string, error) {
+
+	var b bytes.Buffer
+	var size int64
+	var prefix []byte
+
+	for {
+		// The delimiter ends with '>', it always ends a slice
+		slice, err := m.r.ReadSlice('>')
+		if err != nil && err != bufio.ErrBufferFull {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+
+		b.Write(slice)
+		size += int64(len(slice))
+
+		if bytes.HasSuffix(b.Bytes(), []byte(RPCDelimiter)) {
+			size -= int64(len(RPCDelimiter))
+			if prefix != nil || m.tooBig(size) {
+				return string(m.truncate(prefix, b.Bytes())), m.tooBigError()
+			}
+			return string(b.Bytes()[:b.Len()-len(RPCDelimiter)]), nil
+		}
+
+		// Only keep the beginning and what may be the start of the delimiter
+		// of a message too big
+		if m.tooBig(size) {
+			if prefix == nil {
+				prefix = m.truncate(nil, b.Bytes())
+			}
+			tail := b.Bytes()
+			if len(tail) > len(RPCDelimiter) {
+				tail = tail[len(tail)-len(RPCDelimiter):]
+			}
+			tail = append([]byte{}, tail...)
+			b.Reset()
+			b.Write(tail)
+		}
+	}
+}
+
+// readChunked reads the chunks of a message up to the end-of-chunks marker
+func (m *messageReader) readChunked() (string, error) {
+
+	var b bytes.Buffer
+	var size int64
+	var prefix []byte
+
+	for {
+		if err := m.expect("\n#"); err != nil {
+			return "", err
+		}
+
+		c, err := m.r.ReadByte()
+		if err != nil {
+			return "", io.ErrUnexpectedEOF
+		}
+
+		if c == '#' {
+			if err := m.expect("\n"); err != nil {
+				return "", err
+			}
+			break
+		}
+
+		m.r.UnreadByte()
+
+		length, err := m.readChunkSize()
+		if err != nil {
+			return "", err
+		}
+
+		size += length
+
+		if !m.tooBig(size) {
+			if _, err := io.CopyN(&b, m.r, length); err != nil {
+				return "", io.ErrUnexpectedEOF
+			}
+			continue
+		}
+
+		// Keep the beginning of the message, up to the maximum size
+		if prefix == nil {
+			n := m.maxSize - int64(b.Len())
+			if _, err := io.CopyN(&b, m.r, n); err != nil {
+				return "", io.ErrUnexpectedEOF
+			}
+			prefix = b.Bytes()
+			length -= n
+		}
+
+		if _, err := m.r.Discard(int(length)); err != nil {
+			return "", io.ErrUnexpectedEOF
+		}
+	}
+
+	if prefix != nil {
+		return string(prefix), m.tooBigError()
+	}
+
+	if b.Len() == 0 {
+		return "", newFramingError("Empty chunked message")
+	}
+
+	return b.String(), nil
+}
+
+// readChunkSize reads the chunk-size of a chunk header and its line feed
+func (m *messageReader) readChunkSize() (int64, error) {
+
+	var length int64
+
+	for i := 0; ; i++ {
+		c, err := m.r.ReadByte()
+		if err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+
+		if c == '\n' && i > 0 {
+			return length, nil
+		}
+
+		if c < '0' || c > '9' || (i == 0 && c == '0') {
+			return 0, newFramingError("Invalid chunk-size in chunk header")
+		}
+
+		length = length*10 + int64(c-'0')
+
+		if length > maxChunkSize {
+			return 0, newFramingError("Invalid chunk-size in chunk header")
+		}
+	}
+}
+
+// expect reads the bytes of s, any other byte is a framing error
+func (m *messageReader) expect(s string) error {
+
+	for i := 0; i < len(s); i++ {
+		c, err := m.r.ReadByte()
+		if err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		if c != s[i] {
+			return newFramingError("Invalid chunk framing")
+		}
+	}
+
+	return nil
+}
+
+func (m *messageReader) tooBig(size int64) bool {
+	return m.maxSize > 0 && size > m.maxSize
+}
+
+// truncate returns the beginning of a message too big, within the maximum size
+func (m *messageReader) truncate(prefix []byte, data []byte) []byte {
+	if prefix != nil {
+		return prefix
+	}
+	if int64(len(data)) > m.maxSize {
+		data = data[:m.maxSize]
+	}
+	return append([]byte{}, data...)
+}
+
+func (m *messageReader) tooBigError() error {
+	return NewRPCError(ErrTypeRPC, ErrTagTooBig, "Message exceeds the maximum size of %d bytes", m.maxSize)
+}
+
+// checkXML rejects the messages with a DTD, too deeply nested elements or too
+// many attributes before they are parsed
+func checkXML(message string) error {
+
+	decoder := xml.NewDecoder(strings.NewReader(message))
+	depth := 0
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return NewRPCError(ErrTypeRPC, ErrTagMalformedMessage, "Invalid XML: %s", err.Error())
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if MaxXMLDepth > 0 && depth > MaxXMLDepth {
+				return NewRPCError(ErrTypeRPC, ErrTagMalformedMessage, "Elements nested deeper than %d levels", MaxXMLDepth)
+			}
+			if MaxXMLAttributes > 0 && len(t.Attr) > MaxXMLAttributes {
+				return NewRPCError(ErrTypeRPC, ErrTagMalformedMessage, "Element %s has more than %d attributes", t.Name.Local, MaxXMLAttributes)
+			}
+		case xml.EndElement:
+			depth--
+		case xml.Directive:
+			return NewRPCError(ErrTypeRPC, ErrTagMalformedMessage, "DTD and entity declarations are not allowed")
+		}
+	}
+}
@@ -0,0 +1,152 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func init(){
+	fmt.Println("+++++ init framing_test +++++")
+}
+
+// errorTag returns the error-tag of an rpc error, or the error message
+func errorTag(err error) string {
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.ErrorTag
+	}
+	return fmt.Sprint(err)
+}
+
+func TestReadMessage(t *testing.T) {
+
+	large := "<rpc message-id=\"1\">" + strings.Repeat("<a/>", 100000) + "</rpc>"
+
+	input := "<hello/>]]>]]>\n" +
+		large + "]]>]]>" +
+		"\n#6\n<rpc>a\n#7\n</rpc>]\n##\n" +
+		"<b/>]]]>]]>"
+
+	reader := newMessageReader(strings.NewReader(input))
+
+	for _, correct := range []string{"<hello/>", large, "<rpc>a</rpc>]", "<b/>]"} {
+		result, err := reader.readMessage()
+		if err != nil || result != correct {
+			t.Errorf("Result was incorrect, got: %.40s (%v), want: %.40s.", result, err, correct)
+		}
+	}
+
+	if _, err := reader.readMessage(); err != io.EOF {
+		t.Errorf("Result was incorrect, got: %v, want: %v.", err, io.EOF)
+	}
+}
+
+func TestReadMessageTooBig(t *testing.T) {
+
+	input := "<rpc message-id=\"101\">" + strings.Repeat("x", 100) + "</rpc>]]>]]>" +
+		"\n#30\n<rpc message-id=\"102\">" + strings.Repeat("x", 8) + "\n#30\n" + strings.Repeat("x", 30) + "\n##\n" +
+		"<rpc message-id=\"103\"/>]]>]]>"
+
+	reader := newMessageReader(strings.NewReader(input))
+	reader.maxSize = 40
+
+	for _, id := range []string{"101", "102"} {
+		result, err := reader.readMessage()
+		if errorTag(err) != ErrTagTooBig {
+			t.Errorf("Result was incorrect, got: %v, want: %s.", err, ErrTagTooBig)
+		}
+		if len(result) != 40 || extractMessageId(result) != id {
+			t.Errorf("Result was incorrect, got: %s, want the first 40 bytes of message %s.", result, id)
+		}
+	}
+
+	result, err := reader.readMessage()
+	if err != nil || result != "<rpc message-id=\"103\"/>" {
+		t.Errorf("Result was incorrect, got: %s (%v), want: %s.", result, err, "<rpc message-id=\"103\"/>")
+	}
+}
+
+func TestReadMessageFramingErrors(t *testing.T) {
+
+	inputs := []string{
+		"\n#0\n\n##\n",
+		"\n#012\n<rpc/>\n##\n",
+		"\n#4294967296\n<rpc/>\n##\n",
+		"\n#6\n<rpc/>\n#x\n",
+		"\n#6\n<rpc/>##\n",
+		"\n##\n",
+	}
+
+	for _, input := range inputs {
+		_, err := newMessageReader(strings.NewReader(input)).readMessage()
+		var framingErr framingError
+		if !errors.As(err, &framingErr) || errorTag(err) != ErrTagMalformedMessage {
+			t.Errorf("Result was incorrect for %q, got: %v, want: %s.", input, err, ErrTagMalformedMessage)
+		}
+	}
+
+	_, err := newMessageReader(strings.NewReader("\n#6\n<rpc")).readMessage()
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Result was incorrect, got: %v, want: %v.", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestCheckXML(t *testing.T) {
+
+	savedDepth, savedAttributes := MaxXMLDepth, MaxXMLAttributes
+	defer func() { MaxXMLDepth, MaxXMLAttributes = savedDepth, savedAttributes }()
+
+	MaxXMLDepth = 4
+	MaxXMLAttributes = 2
+
+	valid := `<?xml version="1.0" encoding="UTF-8"?><rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><get><filter>&lt;</filter></get></rpc>`
+
+	if err := checkXML(valid); err != nil {
+		t.Errorf("Result was incorrect, got: %v, want: no error.", err)
+	}
+
+	invalid := []string{
+		`<!DOCTYPE rpc [<!ENTITY a "aaaa">]><rpc message-id="1">&a;</rpc>`,
+		`<rpc message-id="1"><get>&a;</get></rpc>`,
+		`<rpc message-id="1"><a><b><c><d/></c></b></a></rpc>`,
+		`<rpc message-id="1" a="1" b="2"/>`,
+		`<rpc message-id="1"><get></rpc>`,
+	}
+
+	for _, input := range invalid {
+		if err := checkXML(input); errorTag(err) != ErrTagMalformedMessage {
+			t.Errorf("Result was incorrect for %s, got: %v, want: %s.", input, err, ErrTagMalformedMessage)
+		}
+	}
+}
+
+func TestProcessReplyMalformed(t *testing.T) {
+
+	reply := processReply(SessionRequest{xml: `<!DOCTYPE rpc><rpc message-id="7"/>`})
+
+	if reply.messageId != "7" || !strings.Contains(reply.String(), "<error-tag>malformed-message</error-tag>") || reply.closeSession {
+		t.Errorf("Result was incorrect, got: %s, want: a malformed-message error.", reply.String())
+	}
+
+	reply = processReply(SessionRequest{xml: `<rpc message-id="8">`, err: newFramingError("Invalid chunk framing")})
+
+	if reply.messageId != "8" || !strings.Contains(reply.String(), "<error-tag>malformed-message</error-tag>") || !reply.closeSession {
+		t.Errorf("Result was incorrect, got: %s, want: a malformed-message error closing the session.", reply.String())
+	}
+}
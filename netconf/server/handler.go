@@ -14,13 +14,15 @@
 package server
 
 import (
-	"bufio"
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"regexp"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/antchfx/xmlquery"
@@ -28,7 +30,18 @@ import (
 	"github.com/golang/glog"
 )
 
-var sessionID = 0
+var sessionID uint32
+
+// Returns the session-id of a new session, main allocates it at authentication
+// so that the TACACS+ packets of the login carry it. The counter wraps around
+// skipping 0, session-id is a non-zero uint32, RFC 6241 section 8.1
+func NewSessionID() uint32 {
+	for {
+		if id := atomic.AddUint32(&sessionID, 1); id != 0 {
+			return id
+		}
+	}
+}
 
 const (
 	delimeter   = "]]>]]>"
@@ -39,47 +52,110 @@ type SessionRequest struct{
 	xml string
 	authenticator Authenticator
 	session ssh.Session
+	ctx context.Context
+	err error
+}
+
+// context returns the context of the request, done when the session is closed
+func (r SessionRequest) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
 }
 
 func SessionHandler(s ssh.Session) {
 
-	scanner := bufio.NewScanner(s)
-	scanner.Split(SplitAt)
+	reader := newMessageReader(s)
+
+	id, ok := s.Context().Value("session-id").(uint32)
+	if !ok {
+		id = NewSessionID()
+	}
 
 	// Send server capablities
-	capabilities := string(capabilitesXML())
+	capabilities := string(capabilitesXML(id))
 	s.Write([]byte(capabilities + delimeter))
 
 	// Read client capablities
-	scanner.Scan()
-	err := readCapabilities(scanner.Text())
+	hello, err := reader.readMessage()
+	if err == nil {
+		err = readCapabilities(hello)
+	}
 	if err != nil {
 		writeResponse(s, createErrorResponse("1", err))
 		s.Close()
+		return
 	}
 
+	chunked := chunkedFraming(hello)
+
 	glog.Info("Capabilities exchange success, starting main loop")
 
-	for scanner.Scan() {
-		requestStr := scanner.Text()
+	authenticator := s.Context().Value("auth").(Authenticator)
+	reason := reasonDisconnect
+
+	accounter, accounting := authenticator.(SessionAccounter)
+	stopWatchdog := func() {}
+
+	if accounting {
+		if !accounter.SessionStart() {
+			glog.Warningf("[AUTH] Session accounting start failed user:(%s)", s.User())
+		}
+		stopWatchdog = startAccountingWatchdog(accounter, AccountingWatchdog)
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			reason = reasonInternalError
+			glog.Errorf("Session of user (%s) ended by a panic: %v", s.User(), err)
+		}
+		stopWatchdog()
+		if accounting && !accounter.SessionStop(reason) {
+			glog.Warningf("[AUTH] Session accounting stop failed user:(%s)", s.User())
+		}
+	}()
+
+	pipeline := newSessionPipeline(s.Context(), s, chunked)
+	// At the end of the input, the replies in progress are still written
+	defer func() { pipeline.stop(reason == reasonDisconnect) }()
+
+	for {
+		requestStr, err := reader.readMessage()
+
+		var rpcErr *RPCError
+		if err != nil && !errors.As(err, &rpcErr) {
+			if err != io.EOF {
+				glog.Errorf("Unable to read request: %s", err.Error())
+				reason = reasonTransportError
+			}
+			break
+		}
+
 		glog.Infof("\nReceving request <<< %s >>> \n %s \n\n", time.Now().Local().String(), requestStr)
 		request := SessionRequest{
 			xml : requestStr,
-			authenticator: s.Context().Value("auth").(Authenticator),
+			authenticator: authenticator,
 			session: s,
+			err: err,
+		}
+		if !pipeline.dispatch(request) {
+			var framingErr framingError
+			if errors.As(err, &framingErr) {
+				reason = reasonMalformedMessage
+			} else if pipeline.closing {
+				reason = reasonCloseSession
+			}
+			break
 		}
-		response := process(request)
-		glog.Infof("\nSending response <<< %s >>> \n %s \n\n", time.Now().Local().String(), response)
-		writeResponse(s, response)
 	}
 }
 
-func capabilitesXML() []byte {
+func capabilitesXML(id uint32) []byte {
 
 	var serverHello Hello
 
-	sessionID += 1 // TODO: handle session id out of bounds
-	serverHello.SessionID = sessionID
+	serverHello.SessionID = id
 	serverHello.Capabilities = append(serverHello.Capabilities, CapNetconf10)
 	serverHello.Capabilities = append(serverHello.Capabilities, CapNetconf11)
 
@@ -88,14 +164,18 @@ func capabilitesXML() []byte {
 	serverHello.Capabilities = append(serverHello.Capabilities, CapMonitoring)
 	serverHello.Capabilities = append(serverHello.Capabilities, CapStartup)
 
-	if !yangModulesInit {
-		readYangModules()
+	if TailfActionsEnabled {
+		serverHello.Capabilities = append(serverHello.Capabilities, CapTailfActions)
 	}
 
-	capYangLib := "urn:ietf:params:netconf:capability:yang-library:1.0?module-set-id=" + *YangModules.ModuleSetId
-	serverHello.Capabilities = append(serverHello.Capabilities, capYangLib)
+	_, yangModules := yangLibrary()
+
+	if yangModules.ModuleSetId != nil {
+		capYangLib := "urn:ietf:params:netconf:capability:yang-library:1.0?module-set-id=" + *yangModules.ModuleSetId
+		serverHello.Capabilities = append(serverHello.Capabilities, capYangLib)
+	}
 
-	for _, module := range YangModules.Modules {
+	for _, module := range yangModules.Modules {
 		supportedCap := *module.Namespace + "?module=" + *module.Name + "&revision=" + *module.Revision
 		serverHello.Capabilities = append(serverHello.Capabilities, supportedCap)
 	}
@@ -109,6 +189,10 @@ func readCapabilities(clientCaps string) error {
 
 	// TODO: Handle client caps
 
+	if err := checkXML(clientCaps); err != nil {
+		return err
+	}
+
 	mainNode, err := xmlquery.Parse(strings.NewReader(clientCaps))
 
 	if err != nil {
@@ -125,61 +209,110 @@ func readCapabilities(clientCaps string) error {
 	return nil
 }
 
+// chunkedFraming reports whether the client hello advertises base:1.1, the
+// messages are then chunked, RFC 6242 section 4.1
+func chunkedFraming(clientCaps string) bool {
+
+	mainNode, err := xmlquery.Parse(strings.NewReader(clientCaps))
+
+	if err != nil {
+		return false
+	}
+
+	for _, capability := range xmlquery.Find(mainNode, "//*[local-name() = 'capability']") {
+		if strings.TrimSpace(capability.InnerText()) == CapNetconf11 {
+			return true
+		}
+	}
+
+	return false
+}
+
 func process(request SessionRequest) string {
+	reply := processReply(request)
+	return reply.String()
+}
+
+func processReply(request SessionRequest) (reply rpcReply) {
+
+	defer doRecover(request.xml, &reply)
 
-	defer doRecover(request.session, request.xml)
+	// Framing errors close the session, the next message can not be found
+	if request.err != nil {
+		var framingErr framingError
+		reply = errorReply(extractMessageId(request.xml), request.err)
+		reply.closeSession = errors.As(request.err, &framingErr)
+		return reply
+	}
+
+	if err := checkXML(request.xml); err != nil {
+		return errorReply(extractMessageId(request.xml), err)
+	}
 
 	rpcNode, err := xmlquery.Parse(strings.NewReader(request.xml))
 
 	if err != nil {
-		return createErrorResponse(extractMessageId(request.xml), errors.New("[Malformed XML] Unable to parser request string"))
+		return errorReply(extractMessageId(request.xml), NewRPCError(ErrTypeRPC, ErrTagMalformedMessage, "Unable to parse request"))
 	}
 
 	rootNode := xmlquery.FindOne(rpcNode, "*")
 
 	if rootNode == nil {
-		return createErrorResponse(extractMessageId(request.xml), errors.New("[Malformed XML] Root node not found"))
+		return errorReply(extractMessageId(request.xml), NewRPCError(ErrTypeRPC, ErrTagMalformedMessage, "Root node not found"))
 	}
 
 	messageId := rootNode.SelectAttr("message-id")
 
 	if messageId == "" {
-		return createErrorResponse(extractMessageId(request.xml), errors.New("[Missing data] Unable to read message-id in rpc"))
+		return errorReply(extractMessageId(request.xml), NewRPCError(ErrTypeRPC, ErrTagMissingAttribute, "Unable to read message-id in rpc"))
 	}
 
 	response, err := handleRequest(request, rootNode)
 
 	if err != nil {
-		return createErrorResponse(messageId, err)
+		return errorReply(messageId, err)
 	}
 
-	return CreateResponse(messageId, []byte(response))
+	_, closing := response.(closeSessionBody)
+
+	return rpcReply{messageId: messageId, body: response, closeSession: closing}
 }
 
-func handleRequest(request SessionRequest, rpcXML *xmlquery.Node) (string, error) {
+func handleRequest(request SessionRequest, rpcXML *xmlquery.Node) (replyBody, error) {
 
 	var response string
 	var err error
 
 	typeNode := xmlquery.FindOne(rpcXML, "//*[local-name() = 'rpc']/*") // Get request type 
 
+	if typeNode == nil {
+		return nil, NewRPCError(ErrTypeRPC, ErrTagMissingElement, "Missing operation in rpc").WithBadElement("rpc")
+	}
+
+	// Read-only users can only retrieve data and close their session
+	if userRole(request.authenticator) == RoleReadOnly && !readOnlyOperations[typeNode.Data] && typeNode.Data != "close-session" {
+		return nil, NewRPCError(ErrTypeProtocol, ErrTagAccessDenied, "Operation %s not allowed for read-only users", typeNode.Data)
+	}
+
 	switch typeNode.Data {
 	case "get":
-		response, err = GetRequestHandler(request.authenticator, rpcXML)
+		return GetRequestHandler(request.context(), request.authenticator, rpcXML)
+	case "get-data":
+		return GetDataHandler(request.context(), request.authenticator, rpcXML)
 	case "get-schema":
-		response, err = GetSchemaHandler(rpcXML)
+		response, err = GetSchemaHandler(request.authenticator, rpcXML)
 	case "close-session":
-		time.AfterFunc(1* time.Second, func() {request.session.Close()}) // probably a better way to do this ?
-		return "ok", nil
+		// The session is closed once the reply is written
+		return closeSessionBody{}, nil
 	default:
-		return "", errors.New("Unsupported command")
+		response, err = RPCRequestHandler(request.context(), request.authenticator, typeNode)
 	}
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return response, nil
+	return stringBody(response), nil
 }
 
 func CreateResponseFromNode(request *xmlquery.Node, responsePayload []byte) string {
@@ -196,15 +329,15 @@ func CreateResponse(messageId string, responsePayload []byte) string {
 		reply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="` + messageId + `"><ok/></rpc-reply>`
 	default:
 		reply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="` + messageId + `">` + reply + "</rpc-reply>"
-		reply = strings.ReplaceAll(reply, "&amp;", "&")
 	}
 
 	return declaration + reply
 }
 
+// writeResponse writes a message before the framing is negotiated, ended by the
+// base:1.0 delimiter
 func writeResponse(session ssh.Session, message string) {
-	responseString := fmt.Sprintf(ChunkedMessage, len(message), message)
-	session.Write([]byte(responseString))
+	session.Write([]byte(message + delimeter))
 }
 
 func writeOkResponse(session ssh.Session, id string) {
@@ -212,49 +345,34 @@ func writeOkResponse(session ssh.Session, id string) {
 }
 
 func createErrorXML(err error) string {
-	return fmt.Sprintf("<rpc-error><error-type>rpc</error-type><error-severity>error</error-severity><error-message xml:lang=\"en\">%s</error-message></rpc-error>", err.Error())
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.xml()
+	}
+	return fmt.Sprintf("<rpc-error><error-type>rpc</error-type><error-severity>error</error-severity><error-message xml:lang=\"en\">%s</error-message></rpc-error>", escapeXML(err.Error()))
 }
 
 func createErrorResponse(messageId string, err error) string {
 	return CreateResponse(messageId, []byte(createErrorXML(err)))
 }
 
-func DefaultHandler(s ssh.Session) {
-	fmt.Println("Default ssh is disabled, closing connection")
-	s.Close()
+func errorReply(messageId string, err error) rpcReply {
+	return rpcReply{messageId: messageId, body: stringBody(createErrorXML(err))}
 }
 
-func SplitAt(data []byte, atEOF bool) (advance int, token []byte, err error) {
-
-	if atEOF && len(data) == 0 || len(trimInput(string(data))) == 0 {
-		return 0, nil, nil
-	}
-
-	// Find the index of the input of the separator substring
-	if i := strings.Index(string(data), RPCDelimiter); i >= 0 {
-		return i + len(RPCDelimiter), data[0:i], nil
-	}
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;", "'", "&apos;")
 
-	if i := strings.Index(string(data), ChunkDelimiter); i >= 0 {
-		return i + len(ChunkDelimiter), data[0:i], nil
-	}
-
-	// If at end of file with data return the data
-	if atEOF {
-		return len(data), data, nil
-	}
-
-	return 0, nil, nil
+// escapeXML escapes s for use in XML text and attribute values
+func escapeXML(s string) string {
+	return xmlEscaper.Replace(s)
 }
 
-func trimInput(input string) string {
-	trimmed := strings.Trim(string(input), "\n")
-	trimmed = strings.Trim(string(trimmed), "\r")
-	trimmed = strings.Trim(string(trimmed), " ")
-	return trimmed
+func DefaultHandler(s ssh.Session) {
+	fmt.Println("Default ssh is disabled, closing connection")
+	s.Close()
 }
 
-func doRecover(session ssh.Session, inputStr string) {
+func doRecover(inputStr string, reply *rpcReply) {
 	if err := recover(); err != nil {
 
 		buf := make([]byte, 64<<10)
@@ -263,8 +381,7 @@ func doRecover(session ssh.Session, inputStr string) {
 		glog.Errorf("Runtime error: panic serving NETCONF request (%s)", inputStr)
 		glog.Errorf("Panic data: %v \n\n %s \n\n //Trace end", err, buf)
 
-		errorXML := createErrorXML(errors.New("Unable to handle request"))
-		writeResponse(session, CreateResponse(extractMessageId(inputStr), []byte(errorXML)))
+		*reply = errorReply(extractMessageId(inputStr), errors.New("Unable to handle request"))
 	}
 }
 
@@ -14,7 +14,10 @@
 package server
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"sync/atomic"
 	"testing"
 )
 
@@ -42,6 +45,37 @@ func TestReadCapabilities(t *testing.T) {
 	}
 }
 
+func TestChunkedFraming(t *testing.T) {
+
+	tests := []struct {
+		hello   string
+		chunked bool
+	}{
+		{"<hello xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\"><capabilities><capability>urn:ietf:params:netconf:base:1.1</capability><capability>urn:ietf:params:netconf:base:1.0</capability></capabilities></hello>", true},
+		{"<hello xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\"><capabilities><capability>urn:ietf:params:netconf:base:1.0</capability></capabilities></hello>", false},
+	}
+
+	for _, test := range tests {
+		if result := chunkedFraming(test.hello); result != test.chunked {
+			t.Errorf("Result was incorrect for %s, got: %t, want: %t.", test.hello, result, test.chunked)
+		}
+	}
+}
+
+func TestNewSessionID(t *testing.T) {
+
+	saved := atomic.LoadUint32(&sessionID)
+	defer atomic.StoreUint32(&sessionID, saved)
+
+	atomic.StoreUint32(&sessionID, math.MaxUint32-1)
+
+	for _, want := range []uint32{math.MaxUint32, 1, 2} {
+		if id := NewSessionID(); id != want {
+			t.Errorf("Result was incorrect, got: %d, want: %d.", id, want)
+		}
+	}
+}
+
 func TestCreateResponse(t *testing.T) {
 	
 	id := "752ab2ee-f662-4ec9-9970-f308a80f18f2"
@@ -59,7 +93,13 @@ func TestCreateResponse(t *testing.T) {
 	}
 
 	result = CreateResponse(id,[]byte("This is a test reply &amp; testing"))
-	correct = "<?xml version=\"1.0\" encoding=\"utf-8\"?><rpc-reply xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\" message-id=\"" + id + "\">" + "This is a test reply & testing" + "</rpc-reply>"
+	correct = "<?xml version=\"1.0\" encoding=\"utf-8\"?><rpc-reply xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\" message-id=\"" + id + "\">" + "This is a test reply &amp; testing" + "</rpc-reply>"
+	if result != correct {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+	}
+
+	result = createErrorXML(errors.New("unexpected <hello> & </rpc>"))
+	correct = "<rpc-error><error-type>rpc</error-type><error-severity>error</error-severity><error-message xml:lang=\"en\">unexpected &lt;hello&gt; &amp; &lt;/rpc&gt;</error-message></rpc-error>"
 	if result != correct {
 		t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
 	}
@@ -98,7 +138,7 @@ func TestProcessRequestAuthFailed(t *testing.T) {
 	}
 
 	// Device specific response, change to your testing device correct response
-	correct := "<?xml version=\"1.0\" encoding=\"utf-8\"?><rpc-reply xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\" message-id=\"752ab2ee-f662-4ec9-9970-f308a80f18f2\"><rpc-error><error-type>rpc</error-type><error-severity>error</error-severity><error-message xml:lang=\"en\">[AUTH] Unauthorized access /sonic-vlan:sonic-vlan/VLAN/VLAN_LIST[name=Vlan100]</error-message></rpc-error></rpc-reply>"
+	correct := "<?xml version=\"1.0\" encoding=\"utf-8\"?><rpc-reply xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\" message-id=\"752ab2ee-f662-4ec9-9970-f308a80f18f2\"><rpc-error><error-type>protocol</error-type><error-tag>access-denied</error-tag><error-severity>error</error-severity><error-message xml:lang=\"en\">[AUTH] Unauthorized access /sonic-vlan:sonic-vlan/VLAN/VLAN_LIST[name=Vlan100]</error-message></rpc-error></rpc-reply>"
 
 	result := process(request)
 
@@ -0,0 +1,239 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// treeBody is the merge of translib get responses, from the top level nodes
+type treeBody struct {
+	members map[string]interface{}
+}
+
+func (t *treeBody) writeTo(w io.Writer) error {
+	e := &xmlEncoder{w: w}
+	e.writeMembers(t.members, schemaRoot, "", "")
+	return e.err
+}
+
+// mergeTranslibBodies merges translib responses into a single tree, siblings
+// from several responses share their ancestors
+func mergeTranslibBodies(bodies []*translibBody) *treeBody {
+
+	tree := &treeBody{members: map[string]interface{}{}}
+
+	for _, body := range bodies {
+		mergeMembers(tree.members, rootMembers(body), schemaRoot)
+	}
+
+	return tree
+}
+
+// rootMembers wraps the response of a translib path in its ancestors, member
+// names qualified with their module. The filters of the path are applied to
+// the entries of the requested list.
+func rootMembers(body *translibBody) map[string]interface{} {
+
+	segments := parsePath(body.path)
+
+	if len(segments) == 0 || len(body.members) == 0 {
+		return map[string]interface{}{}
+	}
+
+	modules := make([]string, len(segments))
+	module := ""
+
+	for i, segment := range segments {
+		if segment.module != "" {
+			module = segment.module
+		}
+		modules[i] = module
+	}
+
+	members := qualifyMembers(body.members, modules[len(modules)-1])
+
+	if len(body.filters) != 0 {
+		for _, value := range members {
+			if list, ok := value.([]interface{}); ok {
+				for i, item := range list {
+					if entry, ok := item.(map[string]interface{}); ok {
+						list[i] = filterMembers(entry, body.filters)
+					}
+				}
+			}
+		}
+	}
+
+	for i := len(segments) - 2; i >= 0; i-- {
+
+		name := modules[i] + ":" + segments[i].name
+
+		if len(segments[i].keys) == 0 {
+			members = map[string]interface{}{name: members}
+			continue
+		}
+
+		for _, key := range segments[i].keys {
+			members[modules[i]+":"+key[0]] = key[1]
+		}
+
+		members = map[string]interface{}{name: []interface{}{members}}
+	}
+
+	return members
+}
+
+// qualifyMembers returns a copy of members with every member name qualified by
+// its module, module being the one of the parent
+func qualifyMembers(members map[string]interface{}, module string) map[string]interface{} {
+
+	qualified := make(map[string]interface{}, len(members))
+
+	for name, value := range members {
+
+		memberModule := module
+		if i := strings.Index(name, ":"); i >= 0 {
+			memberModule, name = name[:i], name[i+1:]
+		}
+
+		qualified[memberModule+":"+name] = qualifyValue(value, memberModule)
+	}
+
+	return qualified
+}
+
+func qualifyValue(value interface{}, module string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return qualifyMembers(v, module)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = qualifyValue(item, module)
+		}
+		return list
+	}
+	return value
+}
+
+// mergeMembers merges the qualified members src into dst, instances of the
+// children of schema. List entries with the same keys are merged.
+func mergeMembers(dst map[string]interface{}, src map[string]interface{}, schema *schemaNode) {
+
+	for name, value := range src {
+
+		existing, ok := dst[name]
+
+		if !ok {
+			dst[name] = value
+			continue
+		}
+
+		var node *schemaNode
+		if schema != nil {
+			i := strings.Index(name, ":")
+			node = schema.childInModule(name[i+1:], name[:i])
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if e, ok := existing.(map[string]interface{}); ok {
+				mergeMembers(e, v, node)
+				continue
+			}
+		case []interface{}:
+			if e, ok := existing.([]interface{}); ok && !isEmptyValue(v) {
+				dst[name] = mergeList(e, v, node)
+				continue
+			}
+		}
+
+		dst[name] = value
+	}
+}
+
+// mergeList merges the items of src into dst, list entries with the entry of
+// the same key values and leaf-list values with the same value. dst is indexed
+// once so that merging is linear in the size of the lists.
+func mergeList(dst []interface{}, src []interface{}, node *schemaNode) []interface{} {
+
+	index := make(map[string]int, len(dst))
+
+	for i, item := range dst {
+		if id, ok := itemID(item, node); ok {
+			if _, found := index[id]; !found {
+				index[id] = i
+			}
+		}
+	}
+
+	for _, item := range src {
+
+		id, ok := itemID(item, node)
+
+		if ok {
+			if i, found := index[id]; found {
+				entry, isEntry := item.(map[string]interface{})
+				existing, existingEntry := dst[i].(map[string]interface{})
+				if isEntry && existingEntry {
+					mergeMembers(existing, entry, node)
+				}
+				// A leaf-list value already present is not repeated
+				continue
+			}
+			index[id] = len(dst)
+		}
+
+		dst = append(dst, item)
+	}
+
+	return dst
+}
+
+// itemID returns the identity of a list item, the key values of an entry of
+// list node or a leaf-list value, with their types so that values of different
+// types printing the same differ. ok is false for items never merged, entries
+// missing a key or entries of a keyless list.
+func itemID(item interface{}, node *schemaNode) (string, bool) {
+
+	entry, isEntry := item.(map[string]interface{})
+
+	if !isEntry {
+		return typedValue(item), true
+	}
+
+	if node == nil || node.kind != schemaList || len(node.keys) == 0 {
+		return "", false
+	}
+
+	var b strings.Builder
+
+	for _, key := range node.keys {
+		value, ok := entry[node.module+":"+key]
+		if !ok {
+			return "", false
+		}
+		b.WriteString(typedValue(value))
+	}
+
+	return b.String(), true
+}
+
+// typedValue returns an unambiguous representation of a decoded JSON value and its type
+func typedValue(value interface{}) string {
+	return fmt.Sprintf("%T%q", value, fmt.Sprint(value))
+}
@@ -0,0 +1,110 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func init(){
+	fmt.Println("+++++ init merge_test +++++")
+}
+
+func testTranslibBody(t *testing.T, path string, payload string, filters ...string) *translibBody {
+	members, err := decodeJSON([]byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &translibBody{path: path, members: members, filters: filters}
+}
+
+func TestMergeTranslibBodies(t *testing.T) {
+	withTestSchemaTree(t, func() {
+
+		tree := mergeTranslibBodies([]*translibBody{
+			testTranslibBody(t, "/test-ops:interfaces/interface[name=Ethernet0]/mtu", `{"test-ops:mtu":9100}`),
+			testTranslibBody(t, "/test-ops:interfaces/interface[name=Ethernet4]", `{"test-ops:interface":[{"name":"Ethernet4","mtu":1500,"enabled":false}]}`, "name", "mtu"),
+			testTranslibBody(t, "/test-ops:interfaces/interface[name=Ethernet0]/enabled", `{"test-ops:enabled":true}`),
+			testTranslibBody(t, "/test-ops:interfaces", `{}`),
+		})
+
+		var b strings.Builder
+
+		if err := tree.writeTo(&b); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		correct := "<interfaces xmlns=\"" + nsTestOps + "\">" +
+			"<interface><name>Ethernet0</name><mtu>9100</mtu><enabled>true</enabled></interface>" +
+			"<interface><name>Ethernet4</name><mtu>1500</mtu></interface></interfaces>"
+
+		if b.String() != correct {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", b.String(), correct)
+		}
+	})
+}
+
+func TestMergeList(t *testing.T) {
+
+	list := &schemaNode{name: "interface", module: "test-ops", kind: schemaList, keys: []string{"name"}}
+
+	dst := []interface{}{
+		map[string]interface{}{"test-ops:name": "1", "test-ops:mtu": json.Number("1500")},
+		map[string]interface{}{"test-ops:name": "Ethernet4"},
+	}
+	src := []interface{}{
+		map[string]interface{}{"test-ops:name": "Ethernet4", "test-ops:mtu": json.Number("9100")},
+		map[string]interface{}{"test-ops:name": json.Number("1")},
+		map[string]interface{}{"test-ops:mtu": json.Number("9100")},
+	}
+
+	result := fmt.Sprint(mergeList(dst, src, list))
+	correct := "[map[test-ops:mtu:1500 test-ops:name:1] map[test-ops:mtu:9100 test-ops:name:Ethernet4] map[test-ops:name:1] map[test-ops:mtu:9100]]"
+
+	if result != correct {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+	}
+
+	leafList := &schemaNode{name: "tags", module: "test-ops", kind: schemaLeafList}
+
+	result = fmt.Sprint(mergeList([]interface{}{"a", json.Number("1")}, []interface{}{"1", "a", json.Number("1"), "b"}, leafList))
+
+	if result != "[a 1 1 b]" {
+		t.Errorf("Result was incorrect, got: %s, want: [a 1 1 b].", result)
+	}
+}
+
+func TestParallelGet(t *testing.T) {
+
+	requests := []GetRequest{}
+	for i := 0; i < 10; i++ {
+		requests = append(requests, GetRequest{path: "/operation:operation"})
+	}
+
+	results, err := parallelGet(context.Background(), nil, requests)
+
+	if err != nil || len(results) != len(requests) {
+		t.Fatalf("Result was incorrect, got: %d results %v, want: %d.", len(results), err, len(requests))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = parallelGet(ctx, nil, requests)
+	checkRPCErrorTag(t, err, ErrTagOperationFailed)
+}
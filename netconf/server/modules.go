@@ -0,0 +1,203 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+// Directory holding the YANG modules and submodules served by the server
+var yangDir = "/usr/models/yang"
+
+// All YANG files found in yangDir, indexed by module or submodule name
+var yangFiles map[string][]*yangFile
+
+// yangFile holds the header information of a YANG module or submodule file
+type yangFile struct {
+	name       string
+	revision   string
+	namespace  string
+	prefix     string
+	belongsTo  string
+	submodule  bool
+	path       string
+	imports    map[string]string // prefix -> module name
+	extensions map[string]yinArgument
+}
+
+// yinArgument describes how the argument of a statement is mapped to YIN
+type yinArgument struct {
+	name    string
+	element bool
+}
+
+// loadYangFiles indexes every YANG module and submodule found in yangDir
+func loadYangFiles() {
+
+	yangFiles = make(map[string][]*yangFile)
+
+	paths, err := filepath.Glob(filepath.Join(yangDir, "*.yang"))
+
+	if err != nil {
+		glog.Warningf("Unable to list yang files in %s: %v", yangDir, err)
+		return
+	}
+
+	for _, path := range paths {
+		file, err := parseYangFile(path)
+		if err != nil {
+			glog.Warningf("Skipping yang file %s: %v", path, err)
+			continue
+		}
+		yangFiles[file.name] = append(yangFiles[file.name], file)
+	}
+
+	// Submodules share the namespace of the module they belong to
+	for _, files := range yangFiles {
+		for _, file := range files {
+			if file.submodule {
+				if parent := findYangFile(file.belongsTo, ""); parent != nil {
+					file.namespace = parent.namespace
+				}
+			}
+		}
+	}
+}
+
+func parseYangFile(path string) (*yangFile, error) {
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	statements, err := yang.Parse(string(data), path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(statements) == 0 {
+		return nil, errors.New("No module statement found")
+	}
+
+	root := statements[0]
+
+	if root.Keyword != "module" && root.Keyword != "submodule" {
+		return nil, errors.New("Unexpected top level statement " + root.Keyword)
+	}
+
+	file := &yangFile{
+		name:       root.Argument,
+		submodule:  root.Keyword == "submodule",
+		path:       path,
+		imports:    make(map[string]string),
+		extensions: make(map[string]yinArgument),
+	}
+
+	for _, statement := range root.SubStatements() {
+		switch statement.Keyword {
+		case "namespace":
+			file.namespace = statement.Argument
+		case "prefix":
+			file.prefix = statement.Argument
+		case "belongs-to":
+			file.belongsTo = statement.Argument
+			if prefix := subStatement(statement, "prefix"); prefix != nil {
+				file.prefix = prefix.Argument
+			}
+		case "import":
+			if prefix := subStatement(statement, "prefix"); prefix != nil {
+				file.imports[prefix.Argument] = statement.Argument
+			}
+		case "revision":
+			// Revisions are expected newest first, but do not rely on it
+			if statement.Argument > file.revision {
+				file.revision = statement.Argument
+			}
+		case "extension":
+			argument := yinArgument{}
+			if arg := subStatement(statement, "argument"); arg != nil {
+				argument.name = arg.Argument
+				if yinElement := subStatement(arg, "yin-element"); yinElement != nil {
+					argument.element = yinElement.Argument == "true"
+				}
+			}
+			file.extensions[statement.Argument] = argument
+		}
+	}
+
+	// Fall back to the revision of revision qualified file names (name@revision.yang)
+	base := strings.TrimSuffix(filepath.Base(path), ".yang")
+	if i := strings.Index(base, "@"); i >= 0 && file.revision == "" {
+		file.revision = base[i+1:]
+	}
+
+	return file, nil
+}
+
+func subStatement(statement *yang.Statement, keyword string) *yang.Statement {
+	for _, s := range statement.SubStatements() {
+		if s.Keyword == keyword {
+			return s
+		}
+	}
+	return nil
+}
+
+// findYangFile returns the file of the module or submodule name with the given
+// revision, or the latest revision when revision is empty
+func findYangFile(name string, revision string) *yangFile {
+
+	var found *yangFile
+
+	for _, file := range yangFiles[name] {
+		if revision != "" {
+			if file.revision == revision {
+				return file
+			}
+			continue
+		}
+		if found == nil || file.revision > found.revision {
+			found = file
+		}
+	}
+
+	return found
+}
+
+// yangFilePath returns the path of the YANG file for the module name and revision
+func yangFilePath(name string, revision string) string {
+	if file := findYangFile(name, revision); file != nil {
+		return file.path
+	}
+	return filepath.Join(yangDir, name+".yang")
+}
+
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
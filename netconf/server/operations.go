@@ -21,12 +21,19 @@ const (
 	RPCGetSchemas       = "/netconf-state:netconf-state/schemas"
 	RPCGetYangModules   = "/modules-state:modules-state[xmlns=urn:ietf:params:xml:ns:yang:ietf-yang-library]"
 
+	SchemaFormatYang = "yang"
+	SchemaFormatYin  = "yin"
+
 	RPCDelimiter   = "]]>]]>"
 	ChunkDelimiter = "\n##\n"
 
 	ChunkedMessage = "\n#%d\n%s\n##\n"
 
+	NsNetconfBase       = "urn:ietf:params:xml:ns:netconf:base:1.0"
 	NsNetconfMonitoring = "urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring"
+	NsYin               = "urn:ietf:params:xml:ns:yang:yin:1"
+	NsYang1             = "urn:ietf:params:xml:ns:yang:1"
+	NsNMDA              = "urn:ietf:params:xml:ns:yang:ietf-netconf-nmda"
 	NsTailfActions      = "http://tail-f.com/ns/netconf/actions/1.0"
 
 	CapNetconf10       = "urn:ietf:params:netconf:base:1.0"
@@ -47,20 +54,25 @@ const (
 )
 
 type RPCError struct {
-	XMLName       xml.Name `xml:"rpc-error"`
-	ErrorType     string   `xml:"error-type"`
-	ErrorTag      string   `xml:"error-tag"`
-	ErrorSeverity string   `xml:"error-severity"`
-	ErrorAppTag   string   `xml:"error-app-tag"`
-	ErrorPath     string   `xml:"error-path"`
-	ErrorMessage  string   `xml:"error-message"`
-	ErrorInfo     struct {
-		BadElement   string `xml:"bad-element"`
-		BadAttribute string `xml:"bad-attribute"`
-		BadNamespace string `xml:"bad-namespace"`
-		SessionID    string `xml:"session-id"`
-		InnerXML     []byte `xml:",innerxml"`
-	} `xml:"error-info"`
+	XMLName       xml.Name      `xml:"rpc-error"`
+	ErrorType     string        `xml:"error-type"`
+	ErrorTag      string        `xml:"error-tag"`
+	ErrorSeverity string        `xml:"error-severity"`
+	ErrorAppTag   string        `xml:"error-app-tag,omitempty"`
+	ErrorPath     string        `xml:"error-path,omitempty"`
+	ErrorMessage  string        `xml:"error-message,omitempty"`
+	ErrorInfo     *RPCErrorInfo `xml:"error-info,omitempty"`
+
+	// Namespaces of the prefixes used in ErrorPath
+	PathNamespaces map[string]string `xml:"-"`
+}
+
+type RPCErrorInfo struct {
+	BadElement   string `xml:"bad-element,omitempty"`
+	BadAttribute string `xml:"bad-attribute,omitempty"`
+	BadNamespace string `xml:"bad-namespace,omitempty"`
+	SessionID    string `xml:"session-id,omitempty"`
+	InnerXML     []byte `xml:",innerxml"`
 }
 
 type Filter struct {
@@ -80,7 +92,7 @@ type DefaultsMode string
 type Hello struct {
 	XMLName      xml.Name `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 hello"`
 	Capabilities []string `xml:"capabilities>capability"`
-	SessionID    int      `xml:"session-id,omitempty"`
+	SessionID    uint32   `xml:"session-id,omitempty"`
 }
 
 type Schema struct {
@@ -16,6 +16,7 @@ package server
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"orange/sonic-netconf-server/build/netconf_codegen"
@@ -34,20 +35,51 @@ type Config struct {
 type GetRequest struct {
 	path 		string
 	filters 	[]string
+	depth		uint	// translib depth, 0 for unbounded
+	content		string	// translib content, config, nonconfig or all
 }
 
+// Translib content query parameter values
+const (
+	ContentAll       = "all"
+	ContentConfig    = "config"
+	ContentNonConfig = "nonconfig"
+)
+
+// Filter containers answered by the server itself, not by translib
+var localContainers = map[string]bool{"modules-state": true, "netconf-state": true, "operation": true}
+
 func ParseGetRequest(node *xmlquery.Node) ([]GetRequest, error) {
 	
 	// TODO: check source tag for config source, for now assume always running config
 	// TODO: request path creation assumes parent -> one child structure in filter tag, validation required
 
-	// Start with filter node
+	// Start with filter node, subtree-filter for get-data
 	filterNode := xmlquery.FindOne(node, "//filter")
 
+	if filterNode == nil {
+		filterNode = xmlquery.FindOne(node, "//subtree-filter")
+	}
+
 	if filterNode == nil {
 		return []GetRequest{}, errors.New("[Missing data] Need filter element. Complete configuration retrival currently not supported")
 	}
 
+	depth, content, err := parseQueryParameters(filterNode.Parent)
+
+	if err != nil {
+		return []GetRequest{}, err
+	}
+
+	if content == "" && filterNode.Parent.Data == "get-data" && datastoreName(filterNode.Parent) == "running" {
+		content = ContentConfig
+	}
+
+	if content == ContentNonConfig && datastoreName(filterNode.Parent) == "running" {
+		// Nothing but config in running
+		return []GetRequest{}, nil
+	}
+
 	queryPaths := []GetRequest{}
 
 	containers := xmlquery.Find(filterNode, "./*")
@@ -56,6 +88,15 @@ func ParseGetRequest(node *xmlquery.Node) ([]GetRequest, error) {
 
 		glog.V(0).Infof("Parsing for model %s started", modelContainer.Data)
 
+		if !localContainers[modelContainer.Data] {
+			ensureSchema()
+			if node := schemaRoot.child(modelContainer.Data, modelContainer.NamespaceURI); node != nil && node.kind != schemaRPC {
+				// Top level node of the module of the filter namespace, e.g. openconfig-interfaces:interfaces
+				queryPaths = append(queryPaths, parseSchemaGetRequests(modelContainer, node, "/"+node.module+":"+node.name)...)
+				continue
+			}
+		}
+
 		// Single request
 		mainPath := "/" + modelContainer.Data + ":" + modelContainer.Data //translib path building
 		
@@ -149,9 +190,144 @@ func ParseGetRequest(node *xmlquery.Node) ([]GetRequest, error) {
 
 	}
 
+	for i := range queryPaths {
+		queryPaths[i].depth = depth
+		queryPaths[i].content = content
+	}
+
 	return queryPaths, nil
 } 
 
+// parseQueryParameters reads the max-depth and config-filter parameters of a
+// get or get-data operation, RFC 8526 section 3.1.1, and checks the datastore
+func parseQueryParameters(op *xmlquery.Node) (uint, string, error) {
+
+	var depth uint
+	content := ""
+
+	if node := xmlquery.FindOne(op, "./*[local-name() = 'max-depth']"); node != nil {
+		value := strings.TrimSpace(node.InnerText())
+		if value != "unbounded" {
+			d, err := strconv.ParseUint(value, 10, 16)
+			if err != nil || d == 0 {
+				return 0, "", NewRPCError(ErrTypeProtocol, ErrTagInvalidValue, "Invalid max-depth %s", value).WithBadElement("max-depth")
+			}
+			depth = uint(d)
+		}
+	}
+
+	if node := xmlquery.FindOne(op, "./*[local-name() = 'config-filter']"); node != nil {
+		switch value := strings.TrimSpace(node.InnerText()); value {
+		case "true":
+			content = ContentConfig
+		case "false":
+			content = ContentNonConfig
+		default:
+			return 0, "", NewRPCError(ErrTypeProtocol, ErrTagInvalidValue, "Invalid config-filter %s", value).WithBadElement("config-filter")
+		}
+	}
+
+	if op.Data == "get-data" && xmlquery.FindOne(op, "./*[local-name() = 'datastore']") == nil {
+		return 0, "", NewRPCError(ErrTypeProtocol, ErrTagMissingElement, "Missing datastore").WithBadElement("datastore")
+	}
+
+	if name := datastoreName(op); op.Data == "get-data" && name != "running" && name != "operational" {
+		return 0, "", NewRPCError(ErrTypeProtocol, ErrTagInvalidValue, "Unsupported datastore %s", name).WithBadElement("datastore")
+	}
+
+	return depth, content, nil
+}
+
+// datastoreName returns the identity of the get-data datastore, e.g. operational
+func datastoreName(op *xmlquery.Node) string {
+
+	node := xmlquery.FindOne(op, "./*[local-name() = 'datastore']")
+
+	if node == nil {
+		return ""
+	}
+
+	name := strings.TrimSpace(node.InnerText())
+
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return name
+}
+
+// parseSchemaGetRequests builds the translib requests of the filter element,
+// an instance of schema node, following the schema. Leaves with a value in a
+// list entry select the entry by key, empty leaves only keep these leaves in
+// the entries.
+func parseSchemaGetRequests(element *xmlquery.Node, node *schemaNode, path string) []GetRequest {
+
+	children := xmlquery.Find(element, "./*")
+
+	if len(children) == 0 || node.kind == schemaLeaf || node.kind == schemaLeafList || node.kind == schemaAnydata {
+		return []GetRequest{{path: path, filters: []string{}}}
+	}
+
+	requests := []GetRequest{}
+	filters := []string{}
+	inner := []*xmlquery.Node{}
+
+	for _, key := range node.keys {
+		keyNode := xmlquery.FindOne(element, "./*[local-name() = '"+key+"']")
+		if keyNode == nil {
+			continue
+		}
+		if value := strings.TrimSpace(keyNode.InnerText()); value != "" {
+			path += "[" + key + "=" + escapePathKey(value) + "]"
+		}
+	}
+
+	for _, child := range children {
+
+		childNode := node.child(child.Data, child.NamespaceURI)
+
+		if childNode == nil {
+			glog.V(0).Infof("Ignoring %s, not in the schema of %s", child.Data, path)
+			continue
+		}
+
+		if node.kind == schemaList && (childNode.kind == schemaLeaf || childNode.kind == schemaLeafList) {
+			if !node.isKey(child.Data) || strings.TrimSpace(child.InnerText()) == "" {
+				filters = append(filters, childNode.name)
+			}
+			continue
+		}
+
+		inner = append(inner, child)
+	}
+
+	if len(inner) == 0 {
+		if len(filters) != 0 {
+			// Keep the keys to identify the entries
+			for _, key := range node.keys {
+				if !contains(filters, key) {
+					filters = append(filters, key)
+				}
+			}
+		}
+		return []GetRequest{{path: path, filters: filters}}
+	}
+
+	for _, child := range inner {
+
+		childNode := node.child(child.Data, child.NamespaceURI)
+		childPath := path + "/" + childNode.name
+
+		if childNode.module != node.module {
+			childPath = path + "/" + childNode.module + ":" + childNode.name
+		}
+
+		requests = append(requests, parseSchemaGetRequests(child, childNode, childPath)...)
+	}
+
+	return requests
+}
+
 func ParseGetSchemaRequest(node *xmlquery.Node) (GetSchema, error) {
 
 	identifier := xmlquery.FindOne(node, "//identifier/text()")
@@ -160,18 +336,22 @@ func ParseGetSchemaRequest(node *xmlquery.Node) (GetSchema, error) {
 
 	s := GetSchema{}
 	
-	if identifier == nil {
-		return s, errors.New("Identifier not passed")
+	if identifier == nil || strings.TrimSpace(identifier.Data) == "" {
+		return s, NewRPCError(ErrTypeProtocol, ErrTagMissingElement, "Identifier not passed").WithBadElement("identifier")
 	}
 
-	s.Identifier = identifier.Data
+	s.Identifier = strings.TrimSpace(identifier.Data)
 
 	if format != nil {
-		s.Format = format.Data
+		// Format is an identity, e.g. ncm:yang
+		s.Format = strings.TrimSpace(format.Data)
+		if i := strings.LastIndex(s.Format, ":"); i >= 0 {
+			s.Format = s.Format[i+1:]
+		}
 	}
 
 	if version != nil {
-		s.Version = version.Data
+		s.Version = strings.TrimSpace(version.Data)
 	}
 
 	return s, nil
@@ -42,4 +42,80 @@ func TestParseGetRequest(t *testing.T){
 	if results[0].path != "/sonic-vlan:sonic-vlan/VLAN" {
 		t.Errorf("Result was incorrect, got: %s, want: %s.", results[0].path, "/sonic-vlan:sonic-vlan/VLAN")
 	}
-}
\ No newline at end of file
+}
+
+func TestParseGetRequestSchema(t *testing.T){
+	withTestAugment(t, func() {
+
+		tests := []struct {
+			filter  string
+			path    string
+			filters []string
+		}{
+			{"<interfaces xmlns=\"" + nsTestOps + "\"/>", "/test-ops:interfaces", []string{}},
+			{"<interfaces xmlns=\"" + nsTestOps + "\"><interface><name/></interface></interfaces>", "/test-ops:interfaces/interface", []string{"name"}},
+			{"<interfaces xmlns=\"" + nsTestOps + "\"><interface><name>Ethernet0</name></interface></interfaces>", "/test-ops:interfaces/interface[name=Ethernet0]", []string{}},
+			{"<interfaces xmlns=\"" + nsTestOps + "\"><interface><name>Ethernet0</name><mtu/><speed xmlns=\"" + nsTestAug + "\"/></interface></interfaces>",
+				"/test-ops:interfaces/interface[name=Ethernet0]", []string{"mtu", "speed", "name"}},
+		}
+
+		for _, test := range tests {
+
+			requestNode, _ := xmlquery.Parse(strings.NewReader("<get><filter type=\"subtree\">" + test.filter + "</filter></get>"))
+
+			results, err := ParseGetRequest(requestNode)
+
+			if err != nil || len(results) != 1 {
+				t.Errorf("Result was incorrect for %s, got: %+v %v, want one request.", test.filter, results, err)
+				continue
+			}
+
+			if results[0].path != test.path || strings.Join(results[0].filters, ",") != strings.Join(test.filters, ",") {
+				t.Errorf("Result was incorrect, got: %s %v, want: %s %v.", results[0].path, results[0].filters, test.path, test.filters)
+			}
+		}
+	})
+}
+
+func TestParseGetRequestQueryParameters(t *testing.T){
+
+	tests := []struct {
+		request string
+		depth   uint
+		content string
+		count   int
+		tag     string
+	}{
+		{"<get><filter><sonic-vlan/></filter></get>", 0, "", 1, ""},
+		{"<get><filter><sonic-vlan/></filter><max-depth>2</max-depth><config-filter>false</config-filter></get>", 2, ContentNonConfig, 1, ""},
+		{"<get-data xmlns:ds=\"urn:ietf:params:xml:ns:yang:ietf-datastores\"><datastore>ds:operational</datastore><subtree-filter><sonic-vlan/></subtree-filter><max-depth>unbounded</max-depth></get-data>", 0, "", 1, ""},
+		{"<get-data><datastore>ds:operational</datastore><subtree-filter><sonic-vlan/></subtree-filter><config-filter>true</config-filter><max-depth>3</max-depth></get-data>", 3, ContentConfig, 1, ""},
+		{"<get-data><datastore>ds:running</datastore><subtree-filter><sonic-vlan/></subtree-filter></get-data>", 0, ContentConfig, 1, ""},
+		{"<get-data><datastore>ds:running</datastore><subtree-filter><sonic-vlan/></subtree-filter><config-filter>false</config-filter></get-data>", 0, "", 0, ""},
+		{"<get-data><datastore>ds:candidate</datastore><subtree-filter><sonic-vlan/></subtree-filter></get-data>", 0, "", 0, ErrTagInvalidValue},
+		{"<get-data><subtree-filter><sonic-vlan/></subtree-filter></get-data>", 0, "", 0, ErrTagMissingElement},
+		{"<get><filter><sonic-vlan/></filter><max-depth>0</max-depth></get>", 0, "", 0, ErrTagInvalidValue},
+		{"<get><filter><sonic-vlan/></filter><config-filter>yes</config-filter></get>", 0, "", 0, ErrTagInvalidValue},
+	}
+
+	for _, test := range tests {
+
+		requestNode, _ := xmlquery.Parse(strings.NewReader(test.request))
+
+		results, err := ParseGetRequest(requestNode)
+
+		if test.tag != "" {
+			checkRPCErrorTag(t, err, test.tag)
+			continue
+		}
+
+		if err != nil || len(results) != test.count {
+			t.Errorf("Result was incorrect for %s, got: %+v %v, want %d requests.", test.request, results, err, test.count)
+			continue
+		}
+
+		if test.count == 1 && (results[0].depth != test.depth || results[0].content != test.content) {
+			t.Errorf("Result was incorrect for %s, got: %d %s, want: %d %s.", test.request, results[0].depth, results[0].content, test.depth, test.content)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// Maximum size in bytes of an rpc-reply, 0 for unlimited. Larger replies are
+// replaced by a too-big rpc-error.
+var MaxResponseSize int64 = 0
+
+// Size of the chunks the replies are streamed in
+var ReplyChunkSize = 64 * 1024
+
+var errResponseTooBig = errors.New("Response too big")
+
+// replyBody is the content of an rpc-reply, encoded while it is written
+type replyBody interface {
+	writeTo(w io.Writer) error
+}
+
+// stringBody is a reply content already encoded, "ok" and "{}" being the
+// <ok/> and empty replies
+type stringBody string
+
+func (s stringBody) writeTo(w io.Writer) error {
+	_, err := io.WriteString(w, string(s))
+	return err
+}
+
+// dataBody wraps parts in a data element
+type dataBody struct {
+	tag   string // opening tag, e.g. <data>
+	parts []replyBody
+}
+
+func (d *dataBody) writeTo(w io.Writer) error {
+
+	if _, err := io.WriteString(w, d.tag); err != nil {
+		return err
+	}
+
+	for _, part := range d.parts {
+		if err := part.writeTo(w); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "</data>")
+	return err
+}
+
+// translibBody is a translib get response, encoded to XML on write
+type translibBody struct {
+	path    string
+	members map[string]interface{}
+	filters []string
+}
+
+func (t *translibBody) writeTo(w io.Writer) error {
+	return encodeGetMembers(w, t.path, t.members, t.filters)
+}
+
+// closeSessionBody is the <ok/> reply to close-session
+type closeSessionBody struct{}
+
+func (closeSessionBody) writeTo(w io.Writer) error {
+	_, err := io.WriteString(w, "<ok/>")
+	return err
+}
+
+// rpcReply is a complete rpc-reply message
+type rpcReply struct {
+	messageId    string
+	body         replyBody
+	closeSession bool // close the session once the reply is written
+}
+
+func (r *rpcReply) writeTo(w io.Writer) error {
+
+	if s, ok := r.body.(stringBody); ok {
+		_, err := io.WriteString(w, CreateResponse(r.messageId, []byte(s)))
+		return err
+	}
+
+	if _, err := io.WriteString(w, declaration+`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="`+r.messageId+`">`); err != nil {
+		return err
+	}
+
+	if err := r.body.writeTo(w); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "</rpc-reply>")
+	return err
+}
+
+func (r *rpcReply) String() string {
+	var b strings.Builder
+	r.writeTo(&b)
+	return b.String()
+}
+
+// writeReply streams reply to w, in chunks when chunked framing was negotiated.
+// With a maximum response size, the reply is encoded once into a buffer bounded
+// by that size and sent once it fits, the encoding stops as soon as it exceeds it.
+func writeReply(w io.Writer, reply *rpcReply, chunked bool) error {
+
+	message := newMessageWriter(w, chunked)
+
+	var err error
+
+	if MaxResponseSize > 0 {
+		err = writeLimitedReply(message, reply)
+	} else {
+		err = reply.writeTo(message)
+	}
+
+	if err != nil {
+		glog.Errorf("Unable to write response to message %s: %v", reply.messageId, err)
+		message.Close()
+		return err
+	}
+
+	return message.Close()
+}
+
+// writeLimitedReply writes reply to message when it does not exceed
+// MaxResponseSize, a too-big rpc-error otherwise
+func writeLimitedReply(message io.Writer, reply *rpcReply) error {
+
+	var b bytes.Buffer
+
+	err := reply.writeTo(&sizeLimiter{w: &b, limit: MaxResponseSize})
+
+	if err == errResponseTooBig {
+		glog.Warningf("Response to message %s exceeds %d bytes", reply.messageId, MaxResponseSize)
+		tooBig := &rpcReply{
+			messageId: reply.messageId,
+			body:      stringBody(createErrorXML(NewRPCError(ErrTypeApplication, ErrTagTooBig, "Response exceeds the maximum size of %d bytes", MaxResponseSize))),
+		}
+		return tooBig.writeTo(message)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = b.WriteTo(message)
+	return err
+}
+
+// sizeLimiter writes to w until more than limit bytes are written
+type sizeLimiter struct {
+	w     io.Writer
+	limit int64
+	size  int64
+}
+
+func (s *sizeLimiter) Write(p []byte) (int, error) {
+	s.size += int64(len(p))
+	if s.size > s.limit {
+		return 0, errResponseTooBig
+	}
+	return s.w.Write(p)
+}
+
+// newMessageWriter returns the writer of a message framed in chunks, or by the
+// end-of-message delimiter when the client only supports base:1.0
+func newMessageWriter(w io.Writer, chunked bool) io.WriteCloser {
+	if chunked {
+		return newChunkWriter(w, ReplyChunkSize)
+	}
+	return &delimitedWriter{w: bufio.NewWriterSize(w, ReplyChunkSize)}
+}
+
+// delimitedWriter writes a message ended by the delimiter of RFC 6242
+// section 4.3. Close ends the message.
+type delimitedWriter struct {
+	w *bufio.Writer
+}
+
+func (d *delimitedWriter) Write(p []byte) (int, error) {
+	return d.w.Write(p)
+}
+
+func (d *delimitedWriter) Close() error {
+
+	if _, err := d.w.WriteString(RPCDelimiter); err != nil {
+		return err
+	}
+
+	return d.w.Flush()
+}
+
+// chunkWriter writes a message in chunks of at most size bytes, RFC 6242
+// section 4.2. Close ends the message.
+type chunkWriter struct {
+	w    io.Writer
+	buf  []byte
+	size int
+}
+
+func newChunkWriter(w io.Writer, size int) *chunkWriter {
+	return &chunkWriter{w: w, buf: make([]byte, 0, size), size: size}
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+
+	n := len(p)
+
+	for len(p) > 0 {
+		free := c.size - len(c.buf)
+		if free > len(p) {
+			free = len(p)
+		}
+		c.buf = append(c.buf, p[:free]...)
+		p = p[free:]
+		if len(c.buf) == c.size {
+			if err := c.flush(); err != nil {
+				return n - len(p), err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+func (c *chunkWriter) flush() error {
+
+	if len(c.buf) == 0 {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(c.w, "\n#%d\n%s", len(c.buf), c.buf)
+	c.buf = c.buf[:0]
+
+	return err
+}
+
+func (c *chunkWriter) Close() error {
+
+	if err := c.flush(); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(c.w, ChunkDelimiter)
+	return err
+}
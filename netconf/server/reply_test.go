@@ -0,0 +1,156 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func init(){
+	fmt.Println("+++++ init reply_test +++++")
+}
+
+func TestChunkWriter(t *testing.T) {
+
+	var b bytes.Buffer
+
+	chunks := newChunkWriter(&b, 4)
+	chunks.Write([]byte("hello"))
+	chunks.Write([]byte(" world"))
+	chunks.Close()
+
+	correct := "\n#4\nhell\n#4\no wo\n#3\nrld\n##\n"
+
+	if b.String() != correct {
+		t.Errorf("Result was incorrect, got: %q, want: %q.", b.String(), correct)
+	}
+}
+
+func TestWriteReply(t *testing.T) {
+	withTestSchemaTree(t, func() {
+
+		savedChunkSize := ReplyChunkSize
+		defer func() { ReplyChunkSize = savedChunkSize }()
+
+		ReplyChunkSize = 16
+
+		members, _ := decodeJSON([]byte(`{"test-ops:interface":[{"name":"Ethernet0","mtu":9100},{"name":"Ethernet4","mtu":1500}]}`))
+
+		reply := &rpcReply{
+			messageId: "101",
+			body: &dataBody{tag: "<data>", parts: []replyBody{
+				&translibBody{path: "/test-ops:interfaces/interface", members: members},
+			}},
+		}
+
+		var b bytes.Buffer
+
+		if err := writeReply(&b, reply, true); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		correct := declaration + "<rpc-reply xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\" message-id=\"101\"><data>" +
+			"<interfaces xmlns=\"" + nsTestOps + "\"><interface><name>Ethernet0</name><mtu>9100</mtu></interface>" +
+			"<interface><name>Ethernet4</name><mtu>1500</mtu></interface></interfaces></data></rpc-reply>"
+
+		if reply.String() != correct {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", reply.String(), correct)
+		}
+
+		// Reassemble the chunks
+		var message strings.Builder
+		rest := b.String()
+		count := 0
+
+		for strings.HasPrefix(rest, "\n#") && !strings.HasPrefix(rest, "\n##\n") {
+			var size int
+			fmt.Sscanf(rest, "\n#%d\n", &size)
+			header := len(fmt.Sprintf("\n#%d\n", size))
+			if size > ReplyChunkSize {
+				t.Errorf("Result was incorrect, chunk of %d bytes", size)
+			}
+			message.WriteString(rest[header : header+size])
+			rest = rest[header+size:]
+			count++
+		}
+
+		if rest != ChunkDelimiter || message.String() != correct || count != (len(correct)+15)/16 {
+			t.Errorf("Result was incorrect, got: %q in %d chunks ending with %q, want: %s.", message.String(), count, rest, correct)
+		}
+	})
+}
+
+func TestWriteReplyTooBig(t *testing.T) {
+
+	savedSize := MaxResponseSize
+	defer func() { MaxResponseSize = savedSize }()
+
+	MaxResponseSize = 200
+
+	var b bytes.Buffer
+
+	writeReply(&b, &rpcReply{messageId: "102", body: stringBody("<data>" + strings.Repeat("<x>1</x>", 50) + "</data>")}, true)
+
+	if !strings.Contains(b.String(), "<error-tag>too-big</error-tag>") {
+		t.Errorf("Result was incorrect, got: %s, want a too-big error.", b.String())
+	}
+
+	b.Reset()
+
+	writeReply(&b, &rpcReply{messageId: "103", body: stringBody("ok")}, true)
+
+	if !strings.Contains(b.String(), "<ok/>") {
+		t.Errorf("Result was incorrect, got: %s, want an ok reply.", b.String())
+	}
+}
+
+func TestWriteReplyDelimited(t *testing.T) {
+	withTestSchemaTree(t, func() {
+
+		savedSize := MaxResponseSize
+		defer func() { MaxResponseSize = savedSize }()
+
+		members, _ := decodeJSON([]byte(`{"test-ops:interface":[{"name":"Ethernet0","mtu":9100},{"name":"Ethernet4","mtu":1500}]}`))
+
+		reply := &rpcReply{
+			messageId: "104",
+			body: &dataBody{tag: "<data>", parts: []replyBody{
+				&translibBody{path: "/test-ops:interfaces/interface", members: members},
+			}},
+		}
+
+		var b bytes.Buffer
+
+		if err := writeReply(&b, reply, false); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if b.String() != reply.String()+RPCDelimiter {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", b.String(), reply.String()+RPCDelimiter)
+		}
+
+		// The encoding stops at the limit, nothing of the reply is sent
+		MaxResponseSize = 100
+		b.Reset()
+
+		writeReply(&b, reply, false)
+
+		if !strings.Contains(b.String(), "<error-tag>too-big</error-tag>") || strings.Contains(b.String(), "<data>") || !strings.HasSuffix(b.String(), RPCDelimiter) {
+			t.Errorf("Result was incorrect, got: %s, want a delimited too-big error.", b.String())
+		}
+	})
+}
@@ -0,0 +1,244 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/Azure/sonic-mgmt-common/translib"
+	"github.com/antchfx/xmlquery"
+	"github.com/golang/glog"
+)
+
+// Accept tail-f style actions, <action xmlns="http://tail-f.com/ns/netconf/actions/1.0">
+// with the data tree in a data element, as sent by NSO
+var TailfActionsEnabled = false
+
+// RPCRequestHandler invokes a YANG rpc, or a YANG 1.1 action wrapped in the
+// yang:1 action element, through translib. ctx is done when the rpc expires
+// or the session is closed.
+func RPCRequestHandler(ctx context.Context, authenticator Authenticator, op *xmlquery.Node) (reply string, err error) {
+
+	ensureSchema()
+
+	var target *schemaNode
+	var inputNode *xmlquery.Node
+	var path string
+
+	tailf := op.Data == "action" && op.NamespaceURI == NsTailfActions && TailfActionsEnabled
+
+	if op.Data == "action" && op.NamespaceURI == NsYang1 || tailf {
+		container := op
+		if tailf {
+			container = xmlquery.FindOne(op, "./*[local-name() = 'data']")
+			if container == nil {
+				return "", NewRPCError(ErrTypeProtocol, ErrTagMissingElement, "Missing data element in action").WithBadElement("data")
+			}
+		}
+		target, inputNode, path, err = resolveAction(container)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		target = findRPC(op.Data, op.NamespaceURI)
+		if target == nil {
+			return "", NewRPCError(ErrTypeProtocol, ErrTagOperationNotSupported, "Unsupported command %s", op.Data)
+		}
+		inputNode = op
+		path = "/" + target.module + ":" + target.name
+	}
+
+	if !authenticator.Authorize(op.Data, path) {
+		return "", NewRPCError(ErrTypeProtocol, ErrTagAccessDenied, "[AUTH] Unauthorized access %s", path)
+	}
+
+	glog.Infof("[AUTH] authorization passed %s", path)
+
+	accounted := accountCommand(authenticator, op.Data, path)
+	defer func() { accounted(err) }()
+
+	payload, err := rpcInput(inputNode, target)
+
+	if err != nil {
+		return "", err
+	}
+
+	glog.Infof("Invoking %s with input %s", path, payload)
+
+	// Nothing is applied once the rpc expired
+	if err := ctx.Err(); err != nil {
+		return "", NewRPCError(ErrTypeApplication, ErrTagOperationFailed, "Request canceled")
+	}
+
+	resp, err := translib.Action(translib.ActionRequest{Path: path, Payload: payload, Ctxt: ctx})
+
+	if err != nil {
+		glog.Errorf("Action %s failed: %v", path, err)
+		return "", NewRPCError(ErrTypeApplication, ErrTagOperationFailed, "%s", err.Error())
+	}
+
+	output, err := rpcOutput(resp.Payload, target)
+
+	if err != nil || !tailf {
+		return output, err
+	}
+
+	return tailfActionReply(target, inputNode, output), nil
+}
+
+// tailfActionReply wraps the action output in the data tree of the request,
+// tail-f actions reply with <data><path-to-action><action>output</action>...</data>
+func tailfActionReply(target *schemaNode, inputNode *xmlquery.Node, output string) string {
+
+	if output == "ok" {
+		return output
+	}
+
+	reply := "<" + target.name + ">" + output + "</" + target.name + ">"
+
+	element := inputNode.Parent
+
+	for schema := target.parent; schema != nil && schema != schemaRoot; schema = schema.parent {
+
+		keys := ""
+		for _, key := range schema.keys {
+			if keyNode := xmlquery.FindOne(element, "./*[local-name() = '"+key+"']"); keyNode != nil {
+				keys += "<" + key + ">" + escapeXML(strings.TrimSpace(keyNode.InnerText())) + "</" + key + ">"
+			}
+		}
+
+		open := "<" + schema.name
+		if schema.parent == schemaRoot || schema.namespace != schema.parent.namespace {
+			open += " xmlns=\"" + escapeXML(schema.namespace) + "\""
+		}
+
+		reply = open + ">" + keys + reply + "</" + schema.name + ">"
+		element = element.Parent
+	}
+
+	return "<data>" + reply + "</data>"
+}
+
+// resolveAction walks the data tree of an action request down to the action
+// node, building the translib path of the action with the list keys
+func resolveAction(op *xmlquery.Node) (*schemaNode, *xmlquery.Node, string, error) {
+
+	schema := schemaRoot
+	element := op
+	path := ""
+
+	for {
+		var next *xmlquery.Node
+
+		for child := element.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != xmlquery.ElementNode || schema.isKey(child.Data) {
+				continue
+			}
+			if next != nil {
+				return nil, nil, "", NewRPCError(ErrTypeProtocol, ErrTagBadElement, "Only one action can be invoked per request").WithBadElement(child.Data)
+			}
+			next = child
+		}
+
+		if next == nil {
+			return nil, nil, "", NewRPCError(ErrTypeProtocol, ErrTagMissingElement, "No action found in request").WithBadElement(element.Data)
+		}
+
+		node := schema.child(next.Data, next.NamespaceURI)
+
+		if node == nil || node.kind == schemaLeaf || node.kind == schemaLeafList || node.kind == schemaRPC {
+			return nil, nil, "", NewRPCError(ErrTypeApplication, ErrTagUnknownElement, "Unknown element %s", next.Data).WithBadElement(next.Data)
+		}
+
+		if schema == schemaRoot || node.module != schema.module {
+			path += "/" + node.module + ":" + node.name
+		} else {
+			path += "/" + node.name
+		}
+
+		if node.kind == schemaAction {
+			return node, next, path, nil
+		}
+
+		for _, key := range node.keys {
+			keyNode := xmlquery.FindOne(next, "./*[local-name() = '"+key+"']")
+			if keyNode == nil {
+				return nil, nil, "", NewRPCError(ErrTypeProtocol, ErrTagMissingElement, "Missing key %s of list %s", key, node.name).WithBadElement(key)
+			}
+			path += "[" + key + "=" + escapePathKey(strings.TrimSpace(keyNode.InnerText())) + "]"
+		}
+
+		schema = node
+		element = next
+	}
+}
+
+var pathKeyEscaper = strings.NewReplacer("\\", "\\\\", "]", "\\]")
+
+// escapePathKey escapes a key value for use in a translib path
+func escapePathKey(s string) string {
+	return pathKeyEscaper.Replace(s)
+}
+
+// rpcInput converts the input parameters of an rpc or action to the
+// translib JSON payload, {"module:input": {...}}
+func rpcInput(element *xmlquery.Node, target *schemaNode) ([]byte, error) {
+
+	input := target.child("input", "")
+
+	if input == nil {
+		return []byte("{}"), nil
+	}
+
+	members, err := xmlToJSON(element, input)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]interface{}{target.module + ":input": members})
+}
+
+// rpcOutput converts the translib output of an rpc or action to the content of
+// the rpc-reply. The output elements are children of rpc-reply, in the base
+// namespace, so they carry the namespace of the module, RFC 7950 section 7.14.4
+func rpcOutput(payload []byte, target *schemaNode) (string, error) {
+
+	members, err := decodeJSON(payload)
+
+	if err != nil {
+		glog.Errorf("Unable to decode output %s: %v", payload, err)
+		return "", NewRPCError(ErrTypeApplication, ErrTagOperationFailed, "Unable to decode output")
+	}
+
+	for name, value := range members {
+		if name == "output" || strings.HasSuffix(name, ":output") {
+			members, _ = value.(map[string]interface{})
+			break
+		}
+	}
+
+	if len(members) == 0 {
+		return "ok", nil
+	}
+
+	var b strings.Builder
+
+	e := &xmlEncoder{w: &b}
+	e.writeMembers(members, target.child("output", ""), target.module, NsNetconfBase)
+
+	return b.String(), e.err
+}
@@ -0,0 +1,239 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+func init(){
+	fmt.Println("+++++ init rpc_test +++++")
+}
+
+const nsTestOps = "http://example.com/test-ops"
+
+// testSchemaNode builds a schema node of module test-ops with children
+func testSchemaNode(name string, kind int, typ yang.TypeKind, children ...*schemaNode) *schemaNode {
+	node := &schemaNode{name: name, module: "test-ops", namespace: nsTestOps, kind: kind, config: true, children: children}
+	if kind == schemaLeaf || kind == schemaLeafList {
+		node.typ = &schemaType{kind: typ}
+	}
+	for _, child := range children {
+		child.parent = node
+	}
+	return node
+}
+
+// withTestSchemaTree uses the test-ops schema tree during f
+func withTestSchemaTree(t *testing.T, f func()) {
+
+	savedRoot, savedNamespaces, savedInit := schemaRoot, schemaNamespaces, schemaInit
+	defer func() {
+		schemaRoot, schemaNamespaces, schemaInit = savedRoot, savedNamespaces, savedInit
+	}()
+
+	iface := testSchemaNode("interface", schemaList, 0,
+		testSchemaNode("name", schemaLeaf, yang.Ystring),
+		testSchemaNode("mtu", schemaLeaf, yang.Yuint16),
+		testSchemaNode("enabled", schemaLeaf, yang.Ybool),
+		testSchemaNode("reset", schemaAction, 0,
+			testSchemaNode("input", schemaInput, 0,
+				testSchemaNode("delay", schemaLeaf, yang.Yuint8)),
+			testSchemaNode("output", schemaOutput, 0,
+				testSchemaNode("result", schemaLeaf, yang.Ystring))))
+	iface.keys = []string{"name"}
+
+	schemaRoot = testSchemaNode("", schemaContainer, 0,
+		testSchemaNode("interfaces", schemaContainer, 0, iface),
+		testSchemaNode("clear-counters", schemaRPC, 0,
+			testSchemaNode("input", schemaInput, 0,
+				testSchemaNode("interface", schemaLeafList, yang.Ystring),
+				testSchemaNode("all", schemaLeaf, yang.Yempty),
+				testSchemaNode("count", schemaLeaf, yang.Yint32),
+				testSchemaNode("options", schemaContainer, 0,
+					testSchemaNode("force", schemaLeaf, yang.Ybool))),
+			testSchemaNode("output", schemaOutput, 0,
+				testSchemaNode("status", schemaLeaf, yang.Ystring),
+				testSchemaNode("cleared", schemaLeaf, yang.Yuint32),
+				testSchemaNode("done", schemaLeaf, yang.Yempty))))
+	schemaRoot.module, schemaRoot.namespace = "", ""
+	schemaNamespaces = map[string]string{nsTestOps: "test-ops"}
+	schemaInit = true
+
+	f()
+}
+
+func parseTestOperation(t *testing.T, operationXML string) *xmlquery.Node {
+	doc, err := xmlquery.Parse(strings.NewReader("<rpc xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\" message-id=\"1\">" + operationXML + "</rpc>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return xmlquery.FindOne(doc, "//*[local-name() = 'rpc']/*")
+}
+
+func TestRPCInput(t *testing.T) {
+	withTestSchemaTree(t, func() {
+
+		op := parseTestOperation(t, "<clear-counters xmlns=\""+nsTestOps+"\"><interface>Ethernet0</interface><interface>Ethernet4</interface><all/><count>10</count><options><force>true</force></options></clear-counters>")
+
+		target := findRPC(op.Data, op.NamespaceURI)
+
+		if target == nil {
+			t.Fatal("Result was incorrect, rpc clear-counters not found")
+		}
+
+		result, err := rpcInput(op, target)
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		correct := `{"test-ops:input":{"all":[null],"count":10,"interface":["Ethernet0","Ethernet4"],"options":{"force":true}}}`
+
+		if string(result) != correct {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+		}
+
+		op = parseTestOperation(t, "<clear-counters xmlns=\""+nsTestOps+"\"><unknown>1</unknown></clear-counters>")
+
+		_, err = rpcInput(op, target)
+		checkRPCErrorTag(t, err, ErrTagUnknownElement)
+	})
+}
+
+func TestRPCOutput(t *testing.T) {
+	withTestSchemaTree(t, func() {
+
+		target := findRPC("clear-counters", nsTestOps)
+
+		result, err := rpcOutput([]byte(`{"test-ops:output":{"cleared":2,"status":"a&b","done":[null]}}`), target)
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		correct := "<status xmlns=\"" + nsTestOps + "\">a&amp;b</status><cleared xmlns=\"" + nsTestOps + "\">2</cleared><done xmlns=\"" + nsTestOps + "\"/>"
+
+		if result != correct {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+		}
+
+		result, _ = rpcOutput([]byte(`{}`), target)
+
+		if result != "ok" {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", result, "ok")
+		}
+	})
+}
+
+func TestResolveAction(t *testing.T) {
+	withTestSchemaTree(t, func() {
+
+		op := parseTestOperation(t, "<action xmlns=\""+NsYang1+"\"><interfaces xmlns=\""+nsTestOps+"\"><interface><name>Ethernet[0]</name><reset><delay>5</delay></reset></interface></interfaces></action>")
+
+		target, input, path, err := resolveAction(op)
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		correctPath := "/test-ops:interfaces/interface[name=Ethernet[0\\]]/reset"
+
+		if path != correctPath {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", path, correctPath)
+		}
+
+		payload, _ := rpcInput(input, target)
+		correct := `{"test-ops:input":{"delay":5}}`
+
+		if string(payload) != correct {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", payload, correct)
+		}
+
+		result, _ := rpcOutput([]byte(`{"test-ops:output":{"result":"done"}}`), target)
+		correct = "<result xmlns=\"" + nsTestOps + "\">done</result>"
+
+		if result != correct {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+		}
+
+		op = parseTestOperation(t, "<action xmlns=\""+NsYang1+"\"><interfaces xmlns=\""+nsTestOps+"\"><interface><reset/></interface></interfaces></action>")
+		_, _, _, err = resolveAction(op)
+		checkRPCErrorTag(t, err, ErrTagMissingElement)
+
+		op = parseTestOperation(t, "<action xmlns=\""+NsYang1+"\"><interfaces xmlns=\""+nsTestOps+"\"><interface><name>Ethernet0</name><mtu>9100</mtu></interface></interfaces></action>")
+		_, _, _, err = resolveAction(op)
+		checkRPCErrorTag(t, err, ErrTagUnknownElement)
+	})
+}
+
+func TestUnsupportedRPC(t *testing.T) {
+	withTestSchemaTree(t, func() {
+		op := parseTestOperation(t, "<reboot xmlns=\""+nsTestOps+"\"/>")
+		_, err := RPCRequestHandler(context.Background(), NewTestAuthenticator(true), op)
+		checkRPCErrorTag(t, err, ErrTagOperationNotSupported)
+	})
+}
+
+func TestTailfAction(t *testing.T) {
+	withTestSchemaTree(t, func() {
+
+		request := "<action xmlns=\"" + NsTailfActions + "\"><data><interfaces xmlns=\"" + nsTestOps + "\"><interface><name>Ethernet0</name><reset><delay>5</delay></reset></interface></interfaces></data></action>"
+
+		TailfActionsEnabled = false
+
+		_, err := RPCRequestHandler(context.Background(), NewTestAuthenticator(true), parseTestOperation(t, request))
+		checkRPCErrorTag(t, err, ErrTagOperationNotSupported)
+
+		if strings.Contains(string(capabilitesXML(1)), CapTailfActions) {
+			t.Errorf("Result was incorrect, %s advertised while disabled", CapTailfActions)
+		}
+
+		TailfActionsEnabled = true
+		defer func() { TailfActionsEnabled = false }()
+
+		if !strings.Contains(string(capabilitesXML(1)), CapTailfActions) {
+			t.Errorf("Result was incorrect, %s not advertised while enabled", CapTailfActions)
+		}
+
+		op := parseTestOperation(t, request)
+		data := xmlquery.FindOne(op, "./*[local-name() = 'data']")
+
+		target, input, path, err := resolveAction(data)
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if path != "/test-ops:interfaces/interface[name=Ethernet0]/reset" {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", path, "/test-ops:interfaces/interface[name=Ethernet0]/reset")
+		}
+
+		result := tailfActionReply(target, input, "<result>done</result>")
+		correct := "<data><interfaces xmlns=\"" + nsTestOps + "\"><interface><name>Ethernet0</name><reset><result>done</result></reset></interface></interfaces></data>"
+
+		if result != correct {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+		}
+
+		_, err = RPCRequestHandler(context.Background(), NewTestAuthenticator(true), parseTestOperation(t, "<action xmlns=\""+NsTailfActions+"\"/>"))
+		checkRPCErrorTag(t, err, ErrTagMissingElement)
+	})
+}
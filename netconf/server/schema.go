@@ -0,0 +1,322 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+// Kinds of schema nodes, choices and cases are flattened into their parent
+const (
+	schemaContainer = iota
+	schemaList
+	schemaLeaf
+	schemaLeafList
+	schemaAnydata
+	schemaRPC
+	schemaAction
+	schemaInput
+	schemaOutput
+)
+
+// schemaNode is the resolved schema tree of the loaded YANG modules, groupings,
+// uses and augments already expanded by goyang
+type schemaNode struct {
+	name      string
+	module    string
+	namespace string
+	kind      int
+	keys      []string
+	config    bool
+	typ       *schemaType
+	children  []*schemaNode
+	parent    *schemaNode
+}
+
+// schemaType is the type of a leaf or leaf-list
+type schemaType struct {
+	kind           yang.TypeKind
+	fractionDigits int
+	union          []*schemaType
+}
+
+var (
+	schemaRoot       *schemaNode
+	schemaNamespaces map[string]string // namespace -> module name
+	schemaInit       = false
+	schemaLock       sync.Mutex
+)
+
+// ensureSchema loads the schema tree of the yang modules once
+func ensureSchema() {
+	schemaLock.Lock()
+	defer schemaLock.Unlock()
+
+	if !schemaInit {
+		yangLibrary()
+		loadSchema()
+	}
+}
+
+func loadSchema() {
+
+	schemaRoot = &schemaNode{kind: schemaContainer}
+	schemaNamespaces = make(map[string]string)
+
+	yang.AddPath(yangDir)
+	modules := yang.NewModules()
+
+	names := []string{}
+
+	for name, files := range yangFiles {
+		if len(files) > 0 && !files[0].submodule {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		file := findYangFile(name, "")
+		schemaNamespaces[file.namespace] = file.name
+		if err := modules.Read(file.path); err != nil {
+			glog.Warningf("Unable to read yang module %s: %v", file.path, err)
+		}
+	}
+
+	for _, err := range modules.Process() {
+		glog.Warningf("Yang module processing error: %v", err)
+	}
+
+	for _, name := range names {
+		module, ok := modules.Modules[name]
+		if !ok {
+			continue
+		}
+		entry := yang.ToEntry(module)
+		if entry == nil {
+			continue
+		}
+		for _, child := range sortedEntries(entry) {
+			addSchemaNode(schemaRoot, child)
+		}
+	}
+
+	schemaInit = true
+}
+
+// sortedEntries returns the children of entry, keys first, then in the order of
+// their statements, RFC 7950 sections 7.8.5 and 7.14.2. goyang does not keep
+// that order, it is taken from the statement of entry: the children of a uses
+// take its place, the ones of augments and nested groupings follow by name.
+func sortedEntries(entry *yang.Entry) []*yang.Entry {
+
+	keys := strings.Fields(entry.Key)
+	entries := []*yang.Entry{}
+
+	for _, key := range keys {
+		if child, ok := entry.Dir[key]; ok {
+			entries = append(entries, child)
+		}
+	}
+
+	names := []string{}
+
+	for name := range entry.Dir {
+		if !contains(keys, name) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	order := statementOrder(entry.Node)
+
+	sort.SliceStable(names, func(i, j int) bool {
+		return entryPosition(entry.Dir[names[i]], order) < entryPosition(entry.Dir[names[j]], order)
+	})
+
+	for _, name := range names {
+		entries = append(entries, entry.Dir[name])
+	}
+
+	return entries
+}
+
+// Statements defining the children of a schema node
+var dataDefKeywords = []string{"container", "leaf", "leaf-list", "list", "choice", "case", "anydata", "anyxml", "action", "rpc", "notification", "input", "output"}
+
+// statementOrder returns the position of the data definition and uses
+// statements of node, uses keyed by "uses " and the grouping name
+func statementOrder(node yang.Node) map[string]int {
+
+	order := map[string]int{}
+
+	if node == nil || node.Statement() == nil {
+		return order
+	}
+
+	for i, statement := range node.Statement().SubStatements() {
+		switch {
+		case statement.Keyword == "uses":
+			name := statement.Argument
+			if j := strings.Index(name, ":"); j >= 0 {
+				name = name[j+1:]
+			}
+			order["uses "+name] = i
+		case contains(dataDefKeywords, statement.Keyword):
+			order[statement.Argument] = i
+		}
+	}
+
+	return order
+}
+
+// entryPosition returns the position of child in the statement order of its
+// parent, after all statements when it is not found
+func entryPosition(child *yang.Entry, order map[string]int) int {
+
+	if i, ok := order[child.Name]; ok {
+		return i
+	}
+
+	for node := child.Node; node != nil; node = node.ParentNode() {
+		if node.Kind() == "grouping" {
+			if i, ok := order["uses "+node.NName()]; ok {
+				return i
+			}
+		}
+	}
+
+	return math.MaxInt32
+}
+
+func addSchemaNode(parent *schemaNode, entry *yang.Entry) {
+
+	if entry.IsChoice() || entry.IsCase() {
+		for _, child := range sortedEntries(entry) {
+			addSchemaNode(parent, child)
+		}
+		return
+	}
+
+	node := &schemaNode{
+		name:   entry.Name,
+		config: !entry.ReadOnly(),
+		parent: parent,
+	}
+
+	if ns := entry.Namespace(); ns != nil {
+		node.namespace = ns.Name
+		node.module = schemaNamespaces[ns.Name]
+	}
+
+	children := sortedEntries(entry)
+
+	switch {
+	case entry.RPC != nil:
+		node.kind = schemaAction
+		if parent == schemaRoot {
+			node.kind = schemaRPC
+		}
+		children = nil
+		if entry.RPC.Input != nil {
+			addSchemaIO(node, entry.RPC.Input, "input", schemaInput)
+		}
+		if entry.RPC.Output != nil {
+			addSchemaIO(node, entry.RPC.Output, "output", schemaOutput)
+		}
+	case entry.IsList():
+		node.kind = schemaList
+		node.keys = strings.Fields(entry.Key)
+	case entry.IsLeafList():
+		node.kind = schemaLeafList
+		node.typ = newSchemaType(entry.Type)
+	case entry.IsLeaf():
+		node.kind = schemaLeaf
+		node.typ = newSchemaType(entry.Type)
+	case entry.Kind == yang.AnyDataEntry || entry.Kind == yang.AnyXMLEntry:
+		node.kind = schemaAnydata
+	default:
+		node.kind = schemaContainer
+	}
+
+	parent.children = append(parent.children, node)
+
+	for _, child := range children {
+		addSchemaNode(node, child)
+	}
+}
+
+func addSchemaIO(parent *schemaNode, entry *yang.Entry, name string, kind int) {
+
+	node := &schemaNode{
+		name:      name,
+		module:    parent.module,
+		namespace: parent.namespace,
+		kind:      kind,
+		parent:    parent,
+	}
+
+	parent.children = append(parent.children, node)
+
+	for _, child := range sortedEntries(entry) {
+		addSchemaNode(node, child)
+	}
+}
+
+func newSchemaType(t *yang.YangType) *schemaType {
+
+	if t == nil {
+		return &schemaType{kind: yang.Ystring}
+	}
+
+	st := &schemaType{kind: t.Kind, fractionDigits: t.FractionDigits}
+
+	for _, member := range t.Type {
+		st.union = append(st.union, newSchemaType(member))
+	}
+
+	return st
+}
+
+// child returns the child node name, in namespace when namespace is not empty
+func (n *schemaNode) child(name string, namespace string) *schemaNode {
+	for _, child := range n.children {
+		if child.name == name && (namespace == "" || child.namespace == namespace) {
+			return child
+		}
+	}
+	return nil
+}
+
+// isKey reports whether name is a key leaf of list n
+func (n *schemaNode) isKey(name string) bool {
+	return n.kind == schemaList && contains(n.keys, name)
+}
+
+// findRPC returns the rpc name defined in the module of namespace
+func findRPC(name string, namespace string) *schemaNode {
+	node := schemaRoot.child(name, namespace)
+	if node == nil || node.kind != schemaRPC {
+		return nil
+	}
+	return node
+}
@@ -0,0 +1,73 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+func init(){
+	fmt.Println("+++++ init schema_test +++++")
+}
+
+func TestSortedEntries(t *testing.T) {
+
+	statements, err := yang.Parse(`module test-order {
+		grouping counters { leaf in-octets { type uint64; } }
+		list port {
+			key "name";
+			leaf mtu { type uint16; }
+			uses counters;
+			leaf name { type string; }
+			container state { }
+			leaf admin-status { type string; }
+		}
+	}`, "test-order.yang")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	module := statements[0].SubStatements()
+	grouping := &yang.Grouping{Name: "counters", Source: module[0]}
+	port := &yang.Container{Name: "port", Source: module[1]}
+
+	entry := &yang.Entry{Name: "port", Node: port, Key: "name", Dir: map[string]*yang.Entry{}}
+
+	for _, child := range port.Source.SubStatements() {
+		if child.Keyword == "leaf" || child.Keyword == "container" {
+			entry.Dir[child.Argument] = &yang.Entry{Name: child.Argument, Node: &yang.Leaf{Name: child.Argument, Source: child, Parent: port}}
+		}
+	}
+
+	entry.Dir["in-octets"] = &yang.Entry{Name: "in-octets", Node: &yang.Leaf{Name: "in-octets", Source: grouping.Source.SubStatements()[0], Parent: grouping}}
+	entry.Dir["augmented"] = &yang.Entry{Name: "augmented"}
+
+	names := []string{}
+
+	for _, child := range sortedEntries(entry) {
+		names = append(names, child.Name)
+	}
+
+	result := strings.Join(names, " ")
+	correct := "name mtu in-octets state admin-status augmented"
+
+	if result != correct {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+	}
+}
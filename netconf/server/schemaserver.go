@@ -0,0 +1,156 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const SchemaPathPrefix = "/yang/"
+
+// Base URL of the schema download endpoint advertised in the yang library
+// schema leaf, e.g. https://switch:8443/yang. Empty when the endpoint is disabled.
+var SchemaBaseURL string
+
+// schemaFileName returns the revision qualified file name of a module or submodule
+func schemaFileName(name string, revision string) string {
+	if revision == "" {
+		return name + ".yang"
+	}
+	return name + "@" + revision + ".yang"
+}
+
+// schemaURL returns the download URL of a module or submodule
+func schemaURL(name string, revision string) string {
+	return strings.TrimSuffix(SchemaBaseURL, "/") + "/" + schemaFileName(name, revision)
+}
+
+// Timeouts of the schema download endpoint, a client holding a connection open
+// without completing its request is dropped
+const (
+	schemaReadHeaderTimeout = 10 * time.Second
+	schemaIdleTimeout       = 60 * time.Second
+)
+
+// ServeSchemas starts the read-only schema download endpoint on addr,
+// using TLS when certFile and keyFile are set
+func ServeSchemas(addr string, certFile string, keyFile string) error {
+
+	yangLibrary()
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           SchemaHandler(),
+		ReadHeaderTimeout: schemaReadHeaderTimeout,
+		IdleTimeout:       schemaIdleTimeout,
+	}
+
+	glog.Infof("Serving YANG schemas on %s%s", addr, SchemaPathPrefix)
+
+	if certFile != "" && keyFile != "" {
+		return srv.ListenAndServeTLS(certFile, keyFile)
+	}
+
+	return srv.ListenAndServe()
+}
+
+// SchemaHandler serves the YANG files of the yang library under SchemaPathPrefix
+// by their revision qualified file name. The prefix itself returns the list of
+// available files.
+func SchemaHandler() http.Handler {
+	// No ServeMux here, it would redirect unclean paths instead of rejecting them
+	return http.StripPrefix(SchemaPathPrefix, http.HandlerFunc(serveSchema))
+}
+
+func serveSchema(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.URL.Path == "" {
+		serveSchemaIndex(w, r)
+		return
+	}
+
+	file := lookupSchemaFile(r.URL.Path)
+
+	if file == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := ioutil.ReadFile(file.path)
+
+	if err != nil {
+		glog.Errorf("Unable to read schema %s: %v", file.path, err)
+		http.Error(w, "Schema not available", http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+
+	w.Header().Set("ETag", "\""+hex.EncodeToString(sum[:])+"\"")
+	w.Header().Set("Content-Type", "application/yang")
+
+	// ServeContent handles HEAD, ranges and If-None-Match against the ETag
+	http.ServeContent(w, r, schemaFileName(file.name, file.revision), fileModTime(file.path), bytes.NewReader(data))
+}
+
+// lookupSchemaFile resolves name@revision.yang or name.yang (latest revision)
+func lookupSchemaFile(fileName string) *yangFile {
+
+	if !strings.HasSuffix(fileName, ".yang") || strings.Contains(fileName, "/") {
+		return nil
+	}
+
+	name := strings.TrimSuffix(fileName, ".yang")
+	revision := ""
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, revision = name[:i], name[i+1:]
+	}
+
+	return findYangFile(name, revision)
+}
+
+func serveSchemaIndex(w http.ResponseWriter, r *http.Request) {
+
+	names := []string{}
+
+	for _, files := range yangFiles {
+		for _, file := range files {
+			names = append(names, schemaFileName(file.name, file.revision))
+		}
+	}
+
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if r.Method == http.MethodGet {
+		w.Write([]byte(strings.Join(names, "\n") + "\n"))
+	}
+}
@@ -0,0 +1,141 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func init(){
+	fmt.Println("+++++ init schemaserver_test +++++")
+}
+
+func serveSchemaRequest(method string, target string, etag string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, nil)
+	if etag != "" {
+		request.Header.Set("If-None-Match", etag)
+	}
+	recorder := httptest.NewRecorder()
+	SchemaHandler().ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestSchemaDownload(t *testing.T) {
+	withTestSchemas(t, func() {
+
+		result := serveSchemaRequest("GET", "/yang/test-netconf@2024-01-01.yang", "")
+
+		if result.Code != http.StatusOK || result.Body.String() != testYangModule {
+			t.Fatalf("Result was incorrect, got: %d %s, want: %d %s.", result.Code, result.Body.String(), http.StatusOK, testYangModule)
+		}
+
+		etag := result.Header().Get("ETag")
+
+		if etag == "" {
+			t.Errorf("Result was incorrect, ETag header missing")
+		}
+
+		result = serveSchemaRequest("GET", "/yang/test-netconf@2024-01-01.yang", etag)
+
+		if result.Code != http.StatusNotModified {
+			t.Errorf("Result was incorrect, got: %d, want: %d.", result.Code, http.StatusNotModified)
+		}
+
+		result = serveSchemaRequest("GET", "/yang/test-netconf.yang", "")
+
+		if result.Code != http.StatusOK || result.Header().Get("ETag") != etag {
+			t.Errorf("Result was incorrect, latest revision not served for unqualified file name")
+		}
+
+		result = serveSchemaRequest("GET", "/yang/", "")
+
+		if result.Body.String() != "test-netconf@2024-01-01.yang\n" {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", result.Body.String(), "test-netconf@2024-01-01.yang\n")
+		}
+	})
+}
+
+func TestSchemaDownloadErrors(t *testing.T) {
+	withTestSchemas(t, func() {
+
+		notFound := []string{
+			"/yang/test-netconf@2000-01-01.yang",
+			"/yang/unknown.yang",
+			"/yang/test-netconf@2024-01-01.yin",
+			"/yang/..%2f..%2fetc%2fpasswd.yang",
+		}
+
+		for _, target := range notFound {
+			result := serveSchemaRequest("GET", target, "")
+			if result.Code != http.StatusNotFound {
+				t.Errorf("Result was incorrect for %s, got: %d, want: %d.", target, result.Code, http.StatusNotFound)
+			}
+		}
+
+		result := serveSchemaRequest("PUT", "/yang/test-netconf@2024-01-01.yang", "")
+
+		if result.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Result was incorrect, got: %d, want: %d.", result.Code, http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func TestSchemaURL(t *testing.T) {
+
+	saved := SchemaBaseURL
+	defer func() { SchemaBaseURL = saved }()
+
+	SchemaBaseURL = "https://switch:8443/yang/"
+
+	result := schemaURL("sonic-vlan", "2019-07-02")
+	correct := "https://switch:8443/yang/sonic-vlan@2019-07-02.yang"
+
+	if result != correct {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+	}
+}
+
+func TestModuleSchemaURL(t *testing.T) {
+	withTestSchemas(t, func() {
+
+		saved := SchemaBaseURL
+		defer func() { SchemaBaseURL = saved }()
+
+		SchemaBaseURL = "https://switch:8443/yang"
+
+		// translib may report another revision than the one of the file
+		file := moduleYangFile("test-netconf", "2023-06-01")
+
+		if file == nil {
+			t.Fatal("Result was incorrect, no file found for test-netconf")
+		}
+
+		url := schemaURL(file.name, file.revision)
+		correct := "https://switch:8443/yang/test-netconf@2024-01-01.yang"
+
+		if url != correct {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", url, correct)
+		}
+
+		result := serveSchemaRequest("GET", strings.TrimPrefix(url, "https://switch:8443"), "")
+
+		if result.Code != http.StatusOK {
+			t.Errorf("Result was incorrect, got: %d, want: %d.", result.Code, http.StatusOK)
+		}
+	})
+}
@@ -0,0 +1,297 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Maximum processing time of an rpc, 0 for no limit. Expired rpcs are answered
+// with an operation-failed error.
+var RPCTimeout time.Duration = 0
+
+// Maximum number of rpcs of a session processed concurrently. Only read-only
+// operations run concurrently, the others wait for the previous ones.
+var SessionConcurrency = 4
+
+// Interval of the accounting watchdog records of a session, 0 for none
+var AccountingWatchdog time.Duration = 0
+
+// Reasons of the end of a session in the accounting stop record
+const (
+	reasonCloseSession     = "close-session"
+	reasonDisconnect       = "disconnect"
+	reasonTransportError   = "transport-error"
+	reasonMalformedMessage = "malformed-message"
+	reasonInternalError    = "internal-error"
+)
+
+// Operations without side effects, safe to process concurrently
+var readOnlyOperations = map[string]bool{"get": true, "get-config": true, "get-data": true, "get-schema": true}
+
+// sessionPipeline dispatches the rpcs of a session and writes their replies in
+// the order of the requests
+type sessionPipeline struct {
+	w        io.WriteCloser
+	chunked  bool
+	handle   func(SessionRequest) rpcReply
+	ctx      context.Context
+	cancel   context.CancelFunc
+	replies  chan chan rpcReply
+	slots    chan struct{}
+	inflight sync.WaitGroup
+	done     chan struct{}
+	closing  bool
+}
+
+// newSessionPipeline starts the reply writer of a session, ctx is done when the
+// session transport is closed. The replies are chunked when the client
+// supports base:1.1.
+func newSessionPipeline(ctx context.Context, w io.WriteCloser, chunked bool) *sessionPipeline {
+
+	concurrency := SessionConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	p := &sessionPipeline{
+		w:       w,
+		chunked: chunked,
+		handle:  processReply,
+		replies: make(chan chan rpcReply, concurrency),
+		slots:   make(chan struct{}, concurrency),
+		done:    make(chan struct{}),
+	}
+
+	p.ctx, p.cancel = context.WithCancel(ctx)
+
+	go p.writeReplies()
+
+	return p
+}
+
+// dispatch starts processing request, false when the session is closing and
+// no more requests should be read
+func (p *sessionPipeline) dispatch(request SessionRequest) bool {
+
+	if p.ctx.Err() != nil {
+		return false
+	}
+
+	readOnly := readOnlyOperations[operationName(request.xml)]
+
+	if !readOnly {
+		p.inflight.Wait()
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+	case <-p.ctx.Done():
+		return false
+	}
+
+	reply := make(chan rpcReply, 1)
+
+	select {
+	case p.replies <- reply:
+	case <-p.ctx.Done():
+		<-p.slots
+		return false
+	}
+
+	p.inflight.Add(1)
+
+	run := func() rpcReply {
+		defer p.inflight.Done()
+		defer func() { <-p.slots }()
+		r, finished := p.process(request)
+		reply <- r
+		// An expired request keeps its slot until its handler returns, the
+		// abandoned work counts against the concurrency of the session
+		<-finished
+		return r
+	}
+
+	if readOnly {
+		go run()
+		return true
+	}
+
+	p.closing = run().closeSession
+
+	return !p.closing
+}
+
+// process runs request with the rpc timeout, the reply of an expired or
+// canceled request is an error. The context of the request, given to translib,
+// is canceled on return and finished is closed once the handler returns.
+func (p *sessionPipeline) process(request SessionRequest) (rpcReply, <-chan struct{}) {
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	if RPCTimeout > 0 {
+		ctx, cancel = context.WithTimeout(p.ctx, RPCTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(p.ctx)
+	}
+	defer cancel()
+
+	request.ctx = ctx
+	result := make(chan rpcReply, 1)
+	finished := make(chan struct{})
+	handle := p.handle
+
+	go func() {
+		defer close(finished)
+		result <- handle(request)
+	}()
+
+	select {
+	case reply := <-result:
+		return reply, finished
+	case <-ctx.Done():
+		messageId := extractMessageId(request.xml)
+		if p.ctx.Err() != nil {
+			return errorReply(messageId, NewRPCError(ErrTypeApplication, ErrTagOperationFailed, "Session closed")), finished
+		}
+		glog.Warningf("Request %s timed out after %s", messageId, RPCTimeout)
+		return errorReply(messageId, NewRPCError(ErrTypeApplication, ErrTagOperationFailed, "Request timed out after %s", RPCTimeout)), finished
+	}
+}
+
+// writeReplies writes the replies in the order of the requests until the
+// session is closed
+func (p *sessionPipeline) writeReplies() {
+
+	defer close(p.done)
+
+	// A panic encoding a reply ends the session, not the server
+	defer func() {
+		if err := recover(); err != nil {
+			buf := make([]byte, 64<<10)
+			buf = buf[:runtime.Stack(buf, false)]
+			glog.Errorf("Runtime error: panic writing NETCONF reply, closing the session")
+			glog.Errorf("Panic data: %v \n\n %s \n\n //Trace end", err, buf)
+			p.cancel()
+			p.w.Close()
+		}
+	}()
+
+	for {
+		var reply chan rpcReply
+		var ok bool
+
+		select {
+		case reply, ok = <-p.replies:
+			if !ok {
+				return
+			}
+		case <-p.ctx.Done():
+			return
+		}
+
+		select {
+		case r := <-reply:
+			glog.Infof("\nSending response <<< %s >>> to message %s\n\n", time.Now().Local().String(), r.messageId)
+			if err := writeReply(p.w, &r, p.chunked); err != nil {
+				p.cancel()
+				return
+			}
+			if r.closeSession {
+				glog.Infof("Closing session after message %s", r.messageId)
+				p.cancel()
+				p.w.Close()
+				return
+			}
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// stop waits for the reply writer. The requests in progress are canceled,
+// unless drain is set or the session is closing with the replies to write
+// before close-session.
+func (p *sessionPipeline) stop(drain bool) {
+	if drain || p.closing {
+		p.inflight.Wait()
+		close(p.replies)
+	} else {
+		p.cancel()
+	}
+	<-p.done
+	p.cancel()
+}
+
+// operationName returns the name of the operation element of an rpc
+func operationName(request string) string {
+
+	decoder := xml.NewDecoder(strings.NewReader(request))
+	depth := 0
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 {
+				return t.Name.Local
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// startAccountingWatchdog sends the watchdog records of a session until the
+// returned function is called
+func startAccountingWatchdog(accounter SessionAccounter, interval time.Duration) func() {
+
+	if interval <= 0 {
+		return func() {}
+	}
+
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if !accounter.SessionWatchdog() {
+					glog.Warningf("[AUTH] Session accounting watchdog failed")
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
@@ -0,0 +1,257 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func init() {
+	fmt.Println("+++++ init session_test +++++")
+}
+
+// testSessionWriter records the replies written to a session
+type testSessionWriter struct {
+	sync.Mutex
+	b      bytes.Buffer
+	closed bool
+}
+
+func (w *testSessionWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	return w.b.Write(p)
+}
+
+func (w *testSessionWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	w.closed = true
+	return nil
+}
+
+func (w *testSessionWriter) messageIds() []string {
+	w.Lock()
+	defer w.Unlock()
+	ids := []string{}
+	for _, match := range regexp.MustCompile("rpc-reply [^>]*message-id=\"(\\w+)\"").FindAllStringSubmatch(w.b.String(), -1) {
+		ids = append(ids, match[1])
+	}
+	return ids
+}
+
+func testRequest(id string, operation string) SessionRequest {
+	return SessionRequest{
+		xml:           "<rpc xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\" message-id=\"" + id + "\">" + operation + "</rpc>",
+		authenticator: NewTestAuthenticator(true),
+	}
+}
+
+// slowRequest delays the processing of a request by the duration in its message-id
+func slowRequest(request SessionRequest) rpcReply {
+
+	if operationName(request.xml) == "close-session" {
+		return processReply(request)
+	}
+
+	id := extractMessageId(request.xml)
+	delay, _ := time.ParseDuration(strings.TrimLeft(id, "abcdefghijklmnopqrstuvwxyz") + "ms")
+
+	select {
+	case <-time.After(delay):
+	case <-request.context().Done():
+	}
+
+	return rpcReply{messageId: id, body: stringBody("ok")}
+}
+
+// newTestPipeline starts a pipeline processing slow requests
+func newTestPipeline(ctx context.Context, w *testSessionWriter) *sessionPipeline {
+	pipeline := newSessionPipeline(ctx, w, true)
+	pipeline.handle = slowRequest
+	return pipeline
+}
+
+func TestSessionPipelineOrder(t *testing.T) {
+	w := &testSessionWriter{}
+	pipeline := newTestPipeline(context.Background(), w)
+
+	pipeline.dispatch(testRequest("a60", "<get/>"))
+	pipeline.dispatch(testRequest("b1", "<get/>"))
+	pipeline.dispatch(testRequest("c20", "<get-schema/>"))
+	pipeline.dispatch(testRequest("d1", "<reboot/>"))
+
+	if pipeline.dispatch(testRequest("e1", "<close-session/>")) {
+		t.Errorf("Result was incorrect, requests still read after close-session")
+	}
+
+	pipeline.stop(false)
+
+	correct := "a60,b1,c20,d1,e1"
+
+	if result := strings.Join(w.messageIds(), ","); result != correct {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+	}
+
+	if !w.closed {
+		t.Errorf("Result was incorrect, session not closed after close-session")
+	}
+}
+
+func TestSessionPipelineTimeout(t *testing.T) {
+	saved := RPCTimeout
+	defer func() { RPCTimeout = saved }()
+
+	RPCTimeout = 20 * time.Millisecond
+
+	w := &testSessionWriter{}
+	pipeline := newTestPipeline(context.Background(), w)
+
+	pipeline.dispatch(testRequest("a1000", "<get/>"))
+	pipeline.dispatch(testRequest("b1", "<get/>"))
+	pipeline.dispatch(testRequest("c1", "<close-session/>"))
+
+	select {
+	case <-pipeline.done:
+	case <-time.After(time.Second):
+		t.Fatal("Result was incorrect, pipeline still running")
+	}
+
+	result := w.b.String()
+
+	if !strings.Contains(result, "message-id=\"a1000\"><rpc-error><error-type>application</error-type><error-tag>operation-failed</error-tag>") {
+		t.Errorf("Result was incorrect, got: %s, want an operation-failed error for a1000.", result)
+	}
+
+	if strings.Join(w.messageIds(), ",") != "a1000,b1,c1" {
+		t.Errorf("Result was incorrect, got: %v, want: a1000,b1,c1.", w.messageIds())
+	}
+}
+
+func TestSessionPipelineCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := &testSessionWriter{}
+	pipeline := newTestPipeline(ctx, w)
+
+	pipeline.dispatch(testRequest("a10000", "<get/>"))
+
+	start := time.Now()
+	cancel()
+	pipeline.stop(false)
+	pipeline.inflight.Wait()
+
+	if time.Since(start) > time.Second {
+		t.Errorf("Result was incorrect, in flight request not canceled")
+	}
+
+	if pipeline.dispatch(testRequest("b1", "<get/>")) {
+		t.Errorf("Result was incorrect, request dispatched on a closed session")
+	}
+}
+
+func TestSessionPipelineDrain(t *testing.T) {
+	w := &testSessionWriter{}
+	pipeline := newTestPipeline(context.Background(), w)
+
+	pipeline.dispatch(testRequest("a50", "<get/>"))
+	pipeline.dispatch(testRequest("b1", "<get/>"))
+
+	// End of the input, the replies in progress are written
+	pipeline.stop(true)
+
+	if result := strings.Join(w.messageIds(), ","); result != "a50,b1" {
+		t.Errorf("Result was incorrect, got: %s, want: a50,b1.", result)
+	}
+
+	if strings.Contains(w.b.String(), "rpc-error") {
+		t.Errorf("Result was incorrect, got: %s, want replies without error.", w.b.String())
+	}
+}
+
+// panicBody panics when the reply is encoded
+type panicBody struct{}
+
+func (panicBody) writeTo(w io.Writer) error {
+	panic("encoding failed")
+}
+
+func TestSessionPipelineWritePanic(t *testing.T) {
+	w := &testSessionWriter{}
+	pipeline := newSessionPipeline(context.Background(), w, true)
+	pipeline.handle = func(request SessionRequest) rpcReply {
+		return rpcReply{messageId: extractMessageId(request.xml), body: panicBody{}}
+	}
+
+	pipeline.dispatch(testRequest("a1", "<get/>"))
+
+	select {
+	case <-pipeline.done:
+	case <-time.After(time.Second):
+		t.Fatal("Result was incorrect, pipeline still running")
+	}
+
+	if pipeline.ctx.Err() == nil || !w.closed {
+		t.Errorf("Result was incorrect, session not closed after a panic writing a reply")
+	}
+
+	if pipeline.dispatch(testRequest("b1", "<get/>")) {
+		t.Errorf("Result was incorrect, request dispatched after a panic writing a reply")
+	}
+}
+
+// testSessionAccounter counts the session accounting records
+type testSessionAccounter struct {
+	watchdogs int32
+}
+
+func (a *testSessionAccounter) SessionStart() bool {
+	return true
+}
+
+func (a *testSessionAccounter) SessionWatchdog() bool {
+	atomic.AddInt32(&a.watchdogs, 1)
+	return true
+}
+
+func (a *testSessionAccounter) SessionStop(reason string) bool {
+	return true
+}
+
+func TestAccountingWatchdog(t *testing.T) {
+
+	accounter := &testSessionAccounter{}
+
+	stop := startAccountingWatchdog(accounter, 10*time.Millisecond)
+	time.Sleep(55 * time.Millisecond)
+	stop()
+
+	count := atomic.LoadInt32(&accounter.watchdogs)
+	time.Sleep(30 * time.Millisecond)
+
+	if count < 2 || atomic.LoadInt32(&accounter.watchdogs) != count {
+		t.Errorf("Result was incorrect, got: %d watchdog records, want about 5 before stop and none after.", count)
+	}
+
+	startAccountingWatchdog(accounter, 0)()
+}
@@ -14,29 +14,27 @@
 package server
 
 import (
-	"encoding/json"
+	"context"
 	"encoding/xml"
 	"errors"
-	"fmt"
-	"html"
 	"io/ioutil"
-	"os"
-	"regexp"
 	"strings"
-
-	"orange/sonic-netconf-server/build/netconf_codegen"
+	"sync"
 
 	"github.com/Azure/sonic-mgmt-common/translib"
 	"github.com/antchfx/xmlquery"
-	"github.com/clbanning/mxj/v2"
 	"github.com/go-redis/redis/v7"
 	"github.com/golang/glog"
 )
 
+// Maximum number of translib requests of a get operation run concurrently
+var GetWorkers = 4
+
 var (
 	YangSchemas map[string][]Schema
 	YangModules ModulesState
 	yangModulesInit	= false
+	yangModulesLock	sync.Mutex
 	redisClient	*redis.Client
 )
 
@@ -49,13 +47,28 @@ func init() {
 	})
 }
 
-func readYangModules() {
+// yangLibrary returns the schemas and modules of the yang library, read once,
+// sessions and the schema download endpoint may ask for them concurrently. A
+// failed read is retried by the next caller and replaces them, so they are read
+// under the lock instead of through the package variables.
+func yangLibrary() (map[string][]Schema, ModulesState) {
+	yangModulesLock.Lock()
+	defer yangModulesLock.Unlock()
 
-	// return all schemas in module form
+	if !yangModulesInit {
+		readYangModules()
+	}
+
+	return YangSchemas, YangModules
+}
 
-	schemas := []Schema{}
+func readYangModules() {
 
-	YangSchemas = make(map[string][]Schema)
+	// The files do not depend on translib, index them once even when the
+	// yang library cannot be read yet
+	if yangFiles == nil {
+		loadYangFiles()
+	}
 
 	yangMod, err := translib.GetYanglibInfo()
 
@@ -64,29 +77,28 @@ func readYangModules() {
 		return
 	}
 
-	YangModules.ModuleSetId = yangMod.ModuleSetId
+	// return all schemas in module form
 
-	for module_key, module := range yangMod.Module {
+	schemas := make(map[string][]Schema)
+	modules := ModulesState{ModuleSetId: yangMod.ModuleSetId}
 
-		schema := Schema{}
-		schema.Identifier = strings.ToLower(*module.Name)
-		schema.Version = module_key.Revision
-		schema.Format = "yang"
-		schema.NameSpace = *module.Namespace
-		schema.ModelPath = "/usr/models/yang/" + *module.Name + ".yang"
-		schema.Location = "NETCONF"
+	for module_key, module := range yangMod.Module {
 
-		schemas = append(schemas, schema)
+		revision := module_key.Revision
+		file := moduleYangFile(*module.Name, revision)
+		if file != nil {
+			revision = file.revision
+		}
 
-		YangSchemas[schema.Identifier] = append(YangSchemas[schema.Identifier], schema)
+		addSchemas(schemas, *module.Name, revision, *module.Namespace, yangFilePath(*module.Name, revision))
 
 		mod := Module{}
 
 		mod.Name = module.Name
 		mod.Namespace = module.Namespace
 		mod.Revision = module.Revision
-		if module.Schema == nil {
-			s := ("http://localhost/usr/models/yang/" + *module.Name)
+		if SchemaBaseURL != "" && file != nil {
+			s := schemaURL(file.name, file.revision)
 			mod.Schema = &s
 		} else {
 			mod.Schema = module.Schema
@@ -99,237 +111,210 @@ func readYangModules() {
 			mod.ConformanceType = "import"
 		}
 
-		YangModules.Modules = append(YangModules.Modules, mod)
+		modules.Modules = append(modules.Modules, mod)
+	}
+
+	// Serve imported modules and submodules missing from the translib list
+	for _, files := range yangFiles {
+		for _, file := range files {
+			addSchemas(schemas, file.name, file.revision, file.namespace, file.path)
+		}
 	}
 
+	YangSchemas = schemas
+	YangModules = modules
 	yangModulesInit = true
 }
 
-func GetRequestHandler(authenticator Authenticator, rootNode *xmlquery.Node) (string, error) {
-
-	requests, err := ParseGetRequest(rootNode)
-
-	glog.Infof("Extracted requests %+v", requests)
-
-	if err != nil {
-		return "", err
+// moduleYangFile returns the file of a yang library module. The schema is served
+// and advertised with the revision of the file, the one its download URL
+// resolves to, which can differ from the revision reported by translib.
+func moduleYangFile(name string, revision string) *yangFile {
+	if file := findYangFile(name, revision); file != nil {
+		return file
 	}
+	return findYangFile(name, "")
+}
 
-	// authenticator := context.Value("auth").(Authenticator)
+// addSchemas registers the YANG and YIN schemas of a module or submodule
+func addSchemas(schemas map[string][]Schema, identifier string, version string, namespace string, path string) {
 
-	for _, request := range requests {
-		// Authorize
-		if !authenticator.Authorize("get", request.path) {
-			return "", errors.New(fmt.Sprintf("[AUTH] Unauthorized access %+s", request.path))
+	for _, schema := range schemas[identifier] {
+		if schema.Version == version {
+			return
 		}
-		glog.Infof("[AUTH] authorization passed %+s", request.path)
 	}
 
-	resultStr := "<data>"
-
-	args := ""
-	for _, request := range requests {
-
-		pathResult, err := innerGetHandler(rootNode, request)
-
-		if err != nil {
-			return "", errors.New("Failed to handle request")
-		}
-
-		resultStr += pathResult
-		args += request.path + ", "
-	}
+	for _, format := range []string{SchemaFormatYang, SchemaFormatYin} {
+		schema := Schema{}
+		schema.Identifier = identifier
+		schema.Version = version
+		schema.Format = format
+		schema.NameSpace = namespace
+		schema.ModelPath = path
+		schema.Location = "NETCONF"
 
-	// Account
-	if !authenticator.Account("get", args) {
-		return "", errors.New(fmt.Sprintf("[AUTH] Accounting failed get - args:%s", args))
+		schemas[identifier] = append(schemas[identifier], schema)
 	}
-
-	glog.Infof("[AUTH] Accounting passed - get: %s", args)
-
-	resultStr += "</data>"
-
-	return resultStr, nil
 }
 
-func innerGetHandler(rootNode *xmlquery.Node, request GetRequest) (string, error) {
-
-	switch request.path {
-	case "/modules-state:modules-state":
-		response, err := xml.MarshalIndent(YangModules, "", "   ")
-		if err != nil {
-			return "", errors.New("Unable to read yang modules")
-		}
-		return string(response), nil
-	case "/netconf-state:netconf-state/schemas":
-		requests, _ := ParseGetRequest(rootNode)
-		return getSchemas(requests[0].path), nil
-	case "/operation:operation":
-		return "", nil
-	default:
-		req := translib.GetRequest{Path: request.path}
-		resp, err1 := translib.Get(req)
-		if err1 == nil {
-
-			//TODO: This section needs refactoring, post-translib get request glue code
-
-			translibResponse := string(resp.Payload)
-
-			// Check for empty response
-			if translibResponse == "{}" {
-				return "", nil
-			}
-
-			// Ensure correct translib output
-			r := regexp.MustCompile("\"(.*?)\"")
-			s := r.FindStringSubmatch(translibResponse)
-			if len(s) == 0 {
-				return "", errors.New("Translib parsing error [1]")
-			}
-
-			// Extract module container info
-			r2 := regexp.MustCompile("(\\S+):(\\S+)")
-			s2 := r2.FindStringSubmatch(s[1])
-
-			if len(s2) == 0 {
-				return "", errors.New("Translib parsing error [2]")
-			}
+func GetRequestHandler(ctx context.Context, authenticator Authenticator, rootNode *xmlquery.Node) (replyBody, error) {
+	return getHandler(ctx, authenticator, rootNode, "get", "<data>")
+}
 
-			translibResponse = strings.Replace(translibResponse, s2[0], s2[2], 1)
+// GetDataHandler handles the NMDA get-data operation, RFC 8526
+func GetDataHandler(ctx context.Context, authenticator Authenticator, rootNode *xmlquery.Node) (replyBody, error) {
+	return getHandler(ctx, authenticator, rootNode, "get-data", "<data xmlns=\""+NsNMDA+"\">")
+}
 
-			if s2[1] != s2[2] {
-				// Inner filters used
-				translibResponse = "{\"" + s2[1] + "\":" + translibResponse + "}"
-			}
+// getHandler runs the translib requests of a get or get-data operation, the
+// reply is encoded to XML while it is written to the session
+func getHandler(ctx context.Context, authenticator Authenticator, rootNode *xmlquery.Node, cmd string, dataTag string) (reply replyBody, err error) {
 
-			if len(request.filters) != 0 {
-				glog.V(0).Infof("Filtering translib response %+s with filters %+v", translibResponse, request.filters)
-				filteredResponse, err := filterJson(translibResponse, request)
-				if err != nil {
-					glog.V(0).Infof("Unable to filter response %+v", err)
-					return "", errors.New("Unable to parse request [3]")
-				}
-				glog.V(0).Infof("Filtered response %+s", filteredResponse)
-				translibResponse = filteredResponse
-			}
+	requests, err := ParseGetRequest(rootNode)
 
-			// Convert to xml
-			jsonConv, _ := mxj.NewMapJson([]byte(translibResponse))
+	glog.Infof("Extracted requests %+v", requests)
 
-			conv := postChecks(rootNode, jsonConv)
+	if err != nil {
+		return nil, err
+	}
 
-			xmlPayload, _ := conv.Xml()
+	args := ""
+	for _, request := range requests {
+		// Authorize
+		if !authenticator.Authorize(cmd, request.path) {
+			return nil, NewRPCError(ErrTypeProtocol, ErrTagAccessDenied, "[AUTH] Unauthorized access %s", request.path)
+		}
+		glog.Infof("[AUTH] authorization passed %+s", request.path)
+		args += request.path + ", "
+	}
 
-			xmlPayload = reorderKeys(request.path, xmlPayload)
+	accounted := accountCommand(authenticator, cmd, args)
+	defer func() { accounted(err) }()
 
-			resultStr := string(xmlPayload)
+	results, err := parallelGet(ctx, rootNode, requests)
 
-			namespace := YangSchemas[s2[1]][0].NameSpace
+	if err != nil {
+		return nil, err
+	}
 
-			resultStr = strings.Replace(resultStr, s2[1], s2[1]+" xmlns=\""+namespace+"\"", 1)
+	result := &dataBody{tag: dataTag}
+	bodies := []*translibBody{}
 
-			return resultStr, nil
+	for i := range requests {
+		if body, ok := results[i].(*translibBody); ok {
+			bodies = append(bodies, body)
+		} else {
+			result.parts = append(result.parts, results[i])
 		}
 	}
 
-	return "", nil
-}
+	if len(bodies) != 0 {
+		result.parts = append(result.parts, mergeTranslibBodies(bodies))
+	}
 
-func postChecks(rootNode *xmlquery.Node, jsonConv mxj.Map) mxj.Map {
+	return result, nil
+}
 
-	filterNode := xmlquery.FindOne(rootNode, "//filter")
-	containers := xmlquery.Find(filterNode, "./*")
+// parallelGet runs the requests on at most GetWorkers goroutines, the results
+// are in the order of the requests. Requests not started yet are dropped when
+// ctx is done.
+func parallelGet(ctx context.Context, rootNode *xmlquery.Node, requests []GetRequest) ([]replyBody, error) {
 
-	for _, modelContainer := range containers {
+	workers := GetWorkers
+	if workers < 1 {
+		workers = 1
+	}
 
-		for _, innerContainer := range xmlquery.Find(modelContainer, "./*") {
+	results := make([]replyBody, len(requests))
+	errs := make([]error, len(requests))
+	slots := make(chan struct{}, workers)
 
-			for _, child := range xmlquery.Find(innerContainer, "./*") {
+	var wg sync.WaitGroup
 
-				exists, err := jsonConv.Exists(modelContainer.Data + "." + child.Data)
+	for i, request := range requests {
 
-				if !exists || err != nil {
-					// Not translib edge case, continue
-					continue		
-				}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
 
-				values, err := jsonConv.ValuesForPath(modelContainer.Data + "." + child.Data)
+		wg.Add(1)
 
-				if len(values) == 0 {
-					glog.V(0).Infof("Empty response, restructuring skipped")
-					continue
+		go func(i int, request GetRequest) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer func() {
+				if r := recover(); r != nil {
+					glog.Errorf("Panic getting %s: %v", request.path, r)
+					errs[i] = errors.New("Failed to handle request")
 				}
+			}()
 
-				glog.V(0).Infof("Translib edge case handling encountered, restructuring response (%s) (%s)", modelContainer.Data, child.Data)
+			if ctx.Err() != nil {
+				errs[i] = ctx.Err()
+				return
+			}
 
-				glog.V(0).Infof("Pre conversion %+v", jsonConv)
+			results[i], errs[i] = innerGetHandler(ctx, rootNode, request)
+		}(i, request)
+	}
 
-				temp := jsonConv[modelContainer.Data]
-				jsonConv[modelContainer.Data] = mxj.New()
-				jsonConv[modelContainer.Data].(mxj.Map)[innerContainer.Data] = temp
-
-				glog.V(0).Infof("Post conversion %+v", jsonConv)
-			}
+	wg.Wait()
 
+	for i, err := range errs {
+		if err == context.Canceled || err == context.DeadlineExceeded {
+			glog.Infof("Get %s canceled: %v", requests[i].path, err)
+			return nil, NewRPCError(ErrTypeApplication, ErrTagOperationFailed, "Request canceled")
+		}
+		if err != nil {
+			return nil, errors.New("Failed to handle request")
 		}
 	}
 
-	return jsonConv
+	return results, nil
 }
 
-func filterJson(input string, request GetRequest) (string, error) {
-
-	// Filters are always applied on lists
-
-	glog.V(0).Infof("Filtering input %+s with filters %+v", input, request.filters)
-
-	var i interface{}
-    if err := json.Unmarshal([]byte(input), &i); err != nil {
-        return "", err
-    }
+func innerGetHandler(ctx context.Context, rootNode *xmlquery.Node, request GetRequest) (replyBody, error) {
 
-	// This logic needs a refactor, o(n^4) horrible complexity
-	for _, container := range i.(map[string]interface{}) {
-		// Container level
-		for listKey, list := range container.(map[string]interface{}){
-			glog.V(0).Infof("List key %+v, list %+v", listKey, list)
-
-			r := regexp.MustCompile("/.*/.*/(\\S+)")
-			s := r.FindStringSubmatch(request.path)
+	switch request.path {
+	case "/modules-state:modules-state":
+		_, modules := yangLibrary()
+		response, err := xml.MarshalIndent(modules, "", "   ")
+		if err != nil {
+			return nil, errors.New("Unable to read yang modules")
+		}
+		return stringBody(response), nil
+	case "/netconf-state:netconf-state/schemas":
+		requests, _ := ParseGetRequest(rootNode)
+		return stringBody(getSchemas(requests[0].path)), nil
+	case "/operation:operation":
+		return stringBody(""), nil
+	default:
+		ensureSchema()
+		req := translib.GetRequest{
+			Path:        request.path,
+			QueryParams: translib.QueryParameters{Depth: request.depth, Content: request.content},
+			Ctxt:        ctx,
+		}
+		resp, err := translib.Get(req)
+		if err != nil {
+			glog.Warningf("Translib get %s failed: %v", request.path, err)
+			return stringBody(""), nil
+		}
 
-			if len(s) == 0 {
-				return "", errors.New("Failed to get list name")
-			}
+		// Decoded now, encoding errors cannot happen once the reply is being sent
+		members, err := decodeJSON(resp.Payload)
 
-			listName := s[1]
-
-			if listName == listKey {
-				// This list has a filter, apply it.
-				arr, ok := list.([]interface{})
-				if ok {
-					for _, arrObj := range arr {
-						// Iterate over each element of the obj to see if we need to filter it
-						aa := arrObj.(map[string]interface{})
-						
-						for k, _ := range aa {
-							glog.V(0).Infof("Reorder check %+s %+s", aa , k)
-							// Check if the key exist in the filter, if so keep it. Delete anything else
-							if !contains(request.filters, k) {
-								delete(aa, k)
-							}
-						}
-					}
-				}
-			}
+		if err != nil {
+			glog.Errorf("Unable to decode translib response %s: %v", resp.Payload, err)
+			return nil, errors.New("Translib parsing error")
 		}
-	}
 
-    output, err := json.Marshal(i)
-    if err != nil {
-        return "", err
-    }
-
-	return string(output), nil
+		return &translibBody{path: request.path, members: members, filters: request.filters}, nil
+	}
 }
 
 func contains(s []string, str string) bool {
@@ -342,88 +327,21 @@ func contains(s []string, str string) bool {
 	return false
 }
 
-func reorderKeys(path string, xml []byte) []byte {
-
-	arr, _ := mxj.NewMapXmlSeq(xml)
-
-	for k, keys := range netconf_codegen.SonicMap {
-
-		var keysReversed = make([]string, len(keys))
-
-		for i, k := range keys {
-			keysReversed[len(keys)-i-1] = k
-		}
-
-		if strings.Contains(k, path+"/") || strings.Contains(path+"/", k) {
-
-			pathSplit := strings.Split(k, "/")
-
-			module := strings.Split(pathSplit[1], ":")[0]
-			parent := pathSplit[2]
-			list := pathSplit[3]
-
-			if _, ok := arr[module].(map[string]interface{})[parent]; ok {
-				
-				// Main container request
-
-				listObj := arr[module].(map[string]interface{})[parent].(map[string]interface{})[list]
-
-				switch listObj.(type) {
-					case map[string]interface{}:
-
-						listCast := arr[module].(map[string]interface{})[parent].(map[string]interface{})[list].(map[string]interface{})
-
-						for i, key := range keysReversed {
-							// We must check because in lists with 2 or more keys, if filtering is applied, some keys will not be in listCast
-							if val, ok := listCast[key]; ok {
-								val.(map[string]interface{})["#seq"] = -(i + 1)
-							}
-						}
-
-						arr[module].(map[string]interface{})[parent].(map[string]interface{})[list] = listCast
-
-					case []interface{}:
-
-						listArr := arr[module].(map[string]interface{})[parent].(map[string]interface{})[list].([]interface{})
-
-						for i, value := range listArr {
-
-							listItem := value.(map[string]interface{})
-
-							for i, key := range keysReversed {
-								// We must check because in lists with 2 or more keys, if filtering is applied, some keys will not be in listCast
-								if val, ok := listItem[key]; ok {
-									val.(map[string]interface{})["#seq"] = -(i + 1)
-								}
-							}
-
-							listArr[i] = listItem
-						}
-
-						arr[module].(map[string]interface{})[parent].(map[string]interface{})[list] = listArr
-				}
-
-			}
-		}
-	}
-
-	result, _ := arr.Xml()
-	return result
-}
-
 func getSchemas(xpath string) string {
 	xpath = strings.ToLower(xpath)
 	var netconf_state State
 	var temp Schema
 
+	yangSchemas, _ := yangLibrary()
+
 	if xpath == RPCGetSchemas || xpath == RPCGetSchemas+"/schema" {
-		for _, schemas := range YangSchemas {
+		for _, schemas := range yangSchemas {
 			netconf_state.Schemas = append(netconf_state.Schemas, schemas...)
 		}
 		return prepareSchemasReply(netconf_state)
 	}
 
-	for _, schemas := range YangSchemas {
+	for _, schemas := range yangSchemas {
 		for _, schema := range schemas {
 			temp = Schema{}
 
@@ -454,7 +372,7 @@ func getSchemas(xpath string) string {
 	return prepareSchemasReply(netconf_state)
 }
 
-func GetSchemaHandler(rootNode *xmlquery.Node) (string, error) {
+func GetSchemaHandler(authenticator Authenticator, rootNode *xmlquery.Node) (reply string, err error) {
 
 	req, err := ParseGetSchemaRequest(rootNode)
 
@@ -462,38 +380,92 @@ func GetSchemaHandler(rootNode *xmlquery.Node) (string, error) {
 		return "", err
 	}
 
-	schema := Schema{}
+	if !authenticator.Authorize("get-schema", req.Identifier) {
+		return "", NewRPCError(ErrTypeProtocol, ErrTagAccessDenied, "[AUTH] Unauthorized access %s", req.Identifier)
+	}
+
+	accounted := accountCommand(authenticator, "get-schema", req.Identifier)
+	defer func() { accounted(err) }()
 
-	identifier := strings.ToLower(req.Identifier)
-	format := strings.ToLower(req.Format)
+	schema, err := findSchema(req)
 
-	for _, model := range YangSchemas[identifier] {
-		if (req.Format == "" || model.Format == format) &&
-			(req.Version == "" || model.Version == req.Version) {
-			schema = model
-			break
-		}
+	if err != nil {
+		return "", err
 	}
 
-	yangData := readYangFile(schema.ModelPath)
-	yangData = html.EscapeString(yangData)
+	yangData, err := readYangFile(schema.ModelPath)
 
-	yangData = "<data xmlns=\"urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring\">" + yangData + "</data>"
+	if err != nil {
+		glog.Errorf("Unable to read schema %s: %v", schema.ModelPath, err)
+		return "", NewRPCError(ErrTypeApplication, ErrTagDataMissing, "Schema %s is not available", schema.Identifier)
+	}
+
+	if schema.Format == SchemaFormatYin {
+		file := findYangFile(schema.Identifier, schema.Version)
+		if file == nil {
+			return "", NewRPCError(ErrTypeApplication, ErrTagDataMissing, "Schema %s is not available", schema.Identifier)
+		}
+		yinData, err := yangToYin(file, yangData)
+		if err != nil {
+			glog.Errorf("Unable to convert schema %s to yin: %v", schema.ModelPath, err)
+			return "", NewRPCError(ErrTypeApplication, ErrTagOperationFailed, "Unable to convert schema %s to yin", schema.Identifier)
+		}
+		return "<data xmlns=\"" + NsNetconfMonitoring + "\">" + yinData + "</data>", nil
+	}
 
-	return yangData, nil
+	return "<data xmlns=\"" + NsNetconfMonitoring + "\">" + escapeXML(yangData) + "</data>", nil
 }
 
-func readYangFile(path string) string {
-	yangFile, err := os.Open(path)
-	if err != nil {
-		fmt.Println(err)
+// findSchema returns the schema matching the get-schema parameters, RFC 6022 section 3.1
+func findSchema(req GetSchema) (Schema, error) {
+
+	format := req.Format
+	if format == "" {
+		format = SchemaFormatYang
+	}
+
+	yangSchemas, _ := yangLibrary()
+
+	candidates, ok := yangSchemas[req.Identifier]
+
+	if !ok {
+		// Identifiers used to be matched in lower case, keep accepting them
+		for identifier, schemas := range yangSchemas {
+			if strings.EqualFold(identifier, req.Identifier) {
+				candidates = schemas
+				break
+			}
+		}
+	}
+
+	matches := []Schema{}
+
+	for _, schema := range candidates {
+		if schema.Format == format && (req.Version == "" || schema.Version == req.Version) {
+			matches = append(matches, schema)
+		}
+	}
+
+	if len(matches) == 0 {
+		return Schema{}, NewRPCError(ErrTypeApplication, ErrTagInvalidValue, "No schema found for identifier %s version %s format %s", req.Identifier, req.Version, format)
 	}
 
-	defer yangFile.Close()
+	if len(matches) > 1 {
+		return Schema{}, NewRPCError(ErrTypeApplication, ErrTagOperationFailed, "More than one schema matches identifier %s, version required", req.Identifier).WithAppTag("data-not-unique")
+	}
+
+	return matches[0], nil
+}
 
-	byteValue, _ := ioutil.ReadAll(yangFile)
+func readYangFile(path string) (string, error) {
+
+	byteValue, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return "", err
+	}
 
-	return html.EscapeString(string(byteValue))
+	return string(byteValue), nil
 }
 
 func prepareSchemasReply(st State) string {
@@ -14,11 +14,143 @@
 package server
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antchfx/xmlquery"
 )
 
 func init(){
 	fmt.Println("+++++ init subhandlers_test +++++")	
 }
 
-// TODO
+const testYangModule = `module test-netconf {
+  namespace "http://example.com/test-netconf";
+  prefix tn;
+
+  description "Test & module";
+
+  revision 2024-01-01;
+
+  extension ext {
+    argument value;
+  }
+
+  container top {
+    leaf name {
+      type string;
+    }
+    tn:ext "a<b";
+  }
+}
+`
+
+// withTestSchemas serves testYangModule as the only schema during f
+func withTestSchemas(t *testing.T, f func()) {
+
+	dir, err := ioutil.TempDir("", "netconf-yang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "test-netconf@2024-01-01.yang"), []byte(testYangModule), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	yangModulesLock.Lock()
+	savedDir, savedFiles, savedSchemas, savedInit := yangDir, yangFiles, YangSchemas, yangModulesInit
+	yangModulesInit = true
+	yangModulesLock.Unlock()
+
+	defer func() {
+		yangModulesLock.Lock()
+		yangDir, yangFiles, YangSchemas, yangModulesInit = savedDir, savedFiles, savedSchemas, savedInit
+		yangModulesLock.Unlock()
+	}()
+
+	// The yang library is not read from translib while the fixtures are set
+	yangDir = dir
+	YangSchemas = make(map[string][]Schema)
+	loadYangFiles()
+
+	file := findYangFile("test-netconf", "")
+	if file == nil {
+		t.Fatal("Test module was not loaded")
+	}
+	addSchemas(YangSchemas, file.name, file.revision, file.namespace, file.path)
+
+	f()
+}
+
+func getSchema(t *testing.T, parameters string) (string, error) {
+	requestXML := "<rpc message-id=\"1\"><get-schema xmlns=\"" + NsNetconfMonitoring + "\">" + parameters + "</get-schema></rpc>"
+	requestNode, err := xmlquery.Parse(strings.NewReader(requestXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return GetSchemaHandler(NewTestAuthenticator(true), requestNode)
+}
+
+func checkRPCErrorTag(t *testing.T, err error, tag string) {
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Errorf("Result was incorrect, got: %v, want rpc-error with tag %s.", err, tag)
+		return
+	}
+	if rpcErr.ErrorTag != tag {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", rpcErr.ErrorTag, tag)
+	}
+}
+
+func TestGetSchemaYang(t *testing.T) {
+	withTestSchemas(t, func() {
+		result, err := getSchema(t, "<identifier>test-netconf</identifier><version>2024-01-01</version>")
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		correct := "<data xmlns=\"" + NsNetconfMonitoring + "\">" + escapeXML(testYangModule) + "</data>"
+		if result != correct {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+		}
+	})
+}
+
+func TestGetSchemaYin(t *testing.T) {
+	withTestSchemas(t, func() {
+		result, err := getSchema(t, "<identifier>test-netconf</identifier><format>ncm:yin</format>")
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		correct := "<data xmlns=\"" + NsNetconfMonitoring + "\">" +
+			"<module name=\"test-netconf\" xmlns=\"urn:ietf:params:xml:ns:yang:yin:1\" xmlns:tn=\"http://example.com/test-netconf\">" +
+			"<namespace uri=\"http://example.com/test-netconf\"/><prefix value=\"tn\"/>" +
+			"<description><text>Test &amp; module</text></description>" +
+			"<revision date=\"2024-01-01\"/>" +
+			"<extension name=\"ext\"><argument name=\"value\"/></extension>" +
+			"<container name=\"top\"><leaf name=\"name\"><type name=\"string\"/></leaf><tn:ext value=\"a&lt;b\"/></container>" +
+			"</module></data>"
+		if result != correct {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+		}
+	})
+}
+
+func TestGetSchemaErrors(t *testing.T) {
+	withTestSchemas(t, func() {
+		_, err := getSchema(t, "<identifier>unknown-module</identifier>")
+		checkRPCErrorTag(t, err, ErrTagInvalidValue)
+
+		_, err = getSchema(t, "<identifier>test-netconf</identifier><version>2000-01-01</version>")
+		checkRPCErrorTag(t, err, ErrTagInvalidValue)
+
+		_, err = getSchema(t, "<version>2024-01-01</version>")
+		checkRPCErrorTag(t, err, ErrTagMissingElement)
+	})
+}
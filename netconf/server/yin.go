@@ -0,0 +1,203 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+// Argument mapping of the YANG keywords, RFC 7950 section 13.1
+var yinKeywords = map[string]yinArgument{
+	"action":           {"name", false},
+	"anydata":          {"name", false},
+	"anyxml":           {"name", false},
+	"argument":         {"name", false},
+	"augment":          {"target-node", false},
+	"base":             {"name", false},
+	"belongs-to":       {"module", false},
+	"bit":              {"name", false},
+	"case":             {"name", false},
+	"choice":           {"name", false},
+	"config":           {"value", false},
+	"contact":          {"text", true},
+	"container":        {"name", false},
+	"default":          {"value", false},
+	"description":      {"text", true},
+	"deviate":          {"value", false},
+	"deviation":        {"target-node", false},
+	"enum":             {"name", false},
+	"error-app-tag":    {"value", false},
+	"error-message":    {"value", true},
+	"extension":        {"name", false},
+	"feature":          {"name", false},
+	"fraction-digits":  {"value", false},
+	"grouping":         {"name", false},
+	"identity":         {"name", false},
+	"if-feature":       {"name", false},
+	"import":           {"module", false},
+	"include":          {"module", false},
+	"input":            {},
+	"key":              {"value", false},
+	"leaf":             {"name", false},
+	"leaf-list":        {"name", false},
+	"length":           {"value", false},
+	"list":             {"name", false},
+	"mandatory":        {"value", false},
+	"max-elements":     {"value", false},
+	"min-elements":     {"value", false},
+	"modifier":         {"value", false},
+	"module":           {"name", false},
+	"must":             {"condition", false},
+	"namespace":        {"uri", false},
+	"notification":     {"name", false},
+	"ordered-by":       {"value", false},
+	"organization":     {"text", true},
+	"output":           {},
+	"path":             {"value", false},
+	"pattern":          {"value", false},
+	"position":         {"value", false},
+	"prefix":           {"value", false},
+	"presence":         {"value", false},
+	"range":            {"value", false},
+	"reference":        {"text", true},
+	"refine":           {"target-node", false},
+	"require-instance": {"value", false},
+	"revision":         {"date", false},
+	"revision-date":    {"date", false},
+	"rpc":              {"name", false},
+	"status":           {"value", false},
+	"submodule":        {"name", false},
+	"type":             {"name", false},
+	"typedef":          {"name", false},
+	"unique":           {"tag", false},
+	"units":            {"name", false},
+	"uses":             {"name", false},
+	"value":            {"value", false},
+	"when":             {"condition", false},
+	"yang-version":     {"value", false},
+	"yin-element":      {"value", false},
+}
+
+// yangToYin converts the YANG module or submodule text of file to its YIN
+// representation, RFC 7950 section 13
+func yangToYin(file *yangFile, data string) (string, error) {
+
+	statements, err := yang.Parse(data, file.path)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(statements) == 0 {
+		return "", errors.New("No module statement found")
+	}
+
+	var b strings.Builder
+
+	if err := writeYinStatement(&b, file, statements[0], true); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+func writeYinStatement(b *strings.Builder, file *yangFile, statement *yang.Statement, root bool) error {
+
+	keyword := statement.Keyword
+	argument, ok := yinKeywords[keyword]
+
+	if i := strings.Index(keyword, ":"); i > 0 {
+		// Extension usage, the argument mapping comes from the extension definition
+		argument, ok = yinExtensionArgument(file, keyword[:i], keyword[i+1:])
+	}
+
+	if !ok {
+		return errors.New("Unknown YANG keyword " + keyword)
+	}
+
+	b.WriteString("<" + keyword)
+
+	if statement.HasArgument && !argument.element {
+		b.WriteString(" " + argument.name + "=\"" + escapeXML(statement.Argument) + "\"")
+	}
+
+	if root {
+		b.WriteString(" xmlns=\"" + NsYin + "\"")
+		b.WriteString(" xmlns:" + file.prefix + "=\"" + escapeXML(file.namespace) + "\"")
+		prefixes := make([]string, 0, len(file.imports))
+		for prefix := range file.imports {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+		for _, prefix := range prefixes {
+			if imported := findYangFile(file.imports[prefix], ""); imported != nil {
+				b.WriteString(" xmlns:" + prefix + "=\"" + escapeXML(imported.namespace) + "\"")
+			}
+		}
+	}
+
+	children := statement.SubStatements()
+
+	if len(children) == 0 && !(statement.HasArgument && argument.element) {
+		b.WriteString("/>")
+		return nil
+	}
+
+	b.WriteString(">")
+
+	if statement.HasArgument && argument.element {
+		name := argument.name
+		if i := strings.Index(keyword, ":"); i > 0 {
+			name = keyword[:i+1] + name
+		}
+		b.WriteString("<" + name + ">" + escapeXML(statement.Argument) + "</" + name + ">")
+	}
+
+	for _, child := range children {
+		if err := writeYinStatement(b, file, child, false); err != nil {
+			return err
+		}
+	}
+
+	b.WriteString("</" + keyword + ">")
+
+	return nil
+}
+
+// yinExtensionArgument looks up the argument of extension name defined in the
+// module imported by file with prefix
+func yinExtensionArgument(file *yangFile, prefix string, name string) (yinArgument, bool) {
+
+	module := file.imports[prefix]
+
+	if prefix == file.prefix {
+		module = file.name
+		if file.submodule {
+			module = file.belongsTo
+		}
+	}
+
+	if definition := findYangFile(module, ""); definition != nil {
+		if argument, ok := definition.extensions[name]; ok {
+			return argument, true
+		}
+	}
+
+	// Extension definition not available, keep the argument as an attribute
+	return yinArgument{name: "name"}, true
+}
@@ -0,0 +1,243 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"bytes"
+	"crypto/des"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"errors"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// Challenge and response lengths of the authentication data, RFC 8907 section 5.4.2
+const (
+	chapChallengeSize     = 16
+	chapResponseSize      = 16
+	mschapChallengeSize   = 8
+	mschapv2ChallengeSize = 16
+	mschapResponseSize    = 49
+)
+
+// MS-CHAP response flags telling the NT-Response is used, RFC 2433 section 5
+const mschapUseNT = 0x1
+
+var errChallengeSize = errors.New("Invalid challenge size")
+
+/*
+Returns the data of an authentication start of authenType for the password, with a new random challenge:
+the password itself for ASCII and PAP, the PPP id, challenge and response for CHAP, MS-CHAP and MS-CHAPv2
+*/
+func AuthenData(authenType uint8, user string, password string) ([]byte, error) {
+
+	switch authenType {
+	case AuthenTypeASCII, AuthenTypePAP:
+		return []byte(password), nil
+	case AuthenTypeCHAP:
+		random, err := randomBytes(1 + chapChallengeSize)
+		if err != nil {
+			return nil, err
+		}
+		return CHAPData(random[0], random[1:], password), nil
+	case AuthenTypeMSCHAP:
+		random, err := randomBytes(1 + mschapChallengeSize)
+		if err != nil {
+			return nil, err
+		}
+		return MSCHAPData(random[0], random[1:], password)
+	case AuthenTypeMSCHAPv2:
+		random, err := randomBytes(1 + 2*mschapv2ChallengeSize)
+		if err != nil {
+			return nil, err
+		}
+		return MSCHAPv2Data(random[0], random[1:1+mschapv2ChallengeSize], random[1+mschapv2ChallengeSize:], user, password)
+	}
+
+	return nil, errors.New("Unsupported authentication type")
+}
+
+/*
+Returns the CHAP authentication data, the PPP id, the challenge and the
+MD5 response to the challenge of RFC 1994 section 4.1
+*/
+func CHAPData(id uint8, challenge []byte, password string) []byte {
+
+	data := append([]byte{id}, challenge...)
+
+	return append(data, chapResponse(id, challenge, password)...)
+}
+
+// Checks the response of CHAP authentication data against the password
+func VerifyCHAP(data []byte, password string) bool {
+
+	if len(data) <= 1+chapResponseSize {
+		return false
+	}
+
+	id, challenge, response := data[0], data[1:len(data)-chapResponseSize], data[len(data)-chapResponseSize:]
+
+	return bytes.Equal(response, chapResponse(id, challenge, password))
+}
+
+func chapResponse(id uint8, challenge []byte, password string) []byte {
+
+	hash := md5.New()
+	hash.Write([]byte{id})
+	hash.Write([]byte(password))
+	hash.Write(challenge)
+
+	return hash.Sum(nil)
+}
+
+/*
+Returns the MS-CHAP authentication data, the PPP id, the 8 bytes challenge and
+the 49 bytes response of RFC 2433, with only the NT-Response set
+*/
+func MSCHAPData(id uint8, challenge []byte, password string) ([]byte, error) {
+
+	if len(challenge) != mschapChallengeSize {
+		return nil, errChallengeSize
+	}
+
+	data := append([]byte{id}, challenge...)
+	data = append(data, make([]byte, 24)...) // LM-Response, unused
+	data = append(data, challengeResponse(challenge, ntPasswordHash(password))...)
+
+	return append(data, mschapUseNT), nil
+}
+
+// Checks the NT-Response of MS-CHAP authentication data against the password
+func VerifyMSCHAP(data []byte, password string) bool {
+
+	if len(data) != 1+mschapChallengeSize+mschapResponseSize {
+		return false
+	}
+
+	challenge := data[1 : 1+mschapChallengeSize]
+	response := data[1+mschapChallengeSize:]
+
+	return response[48] == mschapUseNT && bytes.Equal(response[24:48], challengeResponse(challenge, ntPasswordHash(password)))
+}
+
+/*
+Returns the MS-CHAPv2 authentication data, the PPP id, the 16 bytes authenticator challenge and
+the 49 bytes response of RFC 2759: peer challenge, reserved bytes, NT-Response and flags
+*/
+func MSCHAPv2Data(id uint8, challenge []byte, peerChallenge []byte, user string, password string) ([]byte, error) {
+
+	if len(challenge) != mschapv2ChallengeSize || len(peerChallenge) != mschapv2ChallengeSize {
+		return nil, errChallengeSize
+	}
+
+	data := append([]byte{id}, challenge...)
+	data = append(data, peerChallenge...)
+	data = append(data, make([]byte, 8)...)
+	data = append(data, mschapv2Response(challenge, peerChallenge, user, password)...)
+
+	return append(data, 0), nil
+}
+
+// Checks the NT-Response of MS-CHAPv2 authentication data against the user and password
+func VerifyMSCHAPv2(data []byte, user string, password string) bool {
+
+	if len(data) != 1+mschapv2ChallengeSize+mschapResponseSize {
+		return false
+	}
+
+	challenge := data[1 : 1+mschapv2ChallengeSize]
+	response := data[1+mschapv2ChallengeSize:]
+
+	return bytes.Equal(response[24:48], mschapv2Response(challenge, response[:16], user, password))
+}
+
+// mschapv2Response returns the NT-Response of RFC 2759 section 8.1
+func mschapv2Response(challenge []byte, peerChallenge []byte, user string, password string) []byte {
+
+	// The user name is used without its domain
+	if i := strings.LastIndex(user, "\\"); i >= 0 {
+		user = user[i+1:]
+	}
+
+	hash := sha1.New()
+	hash.Write(peerChallenge)
+	hash.Write(challenge)
+	hash.Write([]byte(user))
+
+	return challengeResponse(hash.Sum(nil)[:8], ntPasswordHash(password))
+}
+
+// ntPasswordHash returns the MD4 hash of the UTF-16LE password, RFC 2433 section A.2
+func ntPasswordHash(password string) []byte {
+
+	unicode := []byte{}
+	for _, c := range utf16.Encode([]rune(password)) {
+		unicode = append(unicode, byte(c), byte(c>>8))
+	}
+
+	hash := md4.New()
+	hash.Write(unicode)
+
+	return hash.Sum(nil)
+}
+
+// challengeResponse encrypts the challenge with the three DES keys of the
+// zero padded password hash, RFC 2433 section A.5
+func challengeResponse(challenge []byte, passwordHash []byte) []byte {
+
+	key := make([]byte, 21)
+	copy(key, passwordHash)
+
+	response := make([]byte, 0, 24)
+
+	for i := 0; i < 3; i++ {
+		block, _ := des.NewCipher(desKey(key[7*i : 7*i+7]))
+		encrypted := make([]byte, 8)
+		block.Encrypt(encrypted, challenge)
+		response = append(response, encrypted...)
+	}
+
+	return response
+}
+
+// desKey spreads the 56 bits of a 7 bytes key over the 8 bytes of a DES key,
+// the parity bits are ignored
+func desKey(key []byte) []byte {
+
+	result := make([]byte, 8)
+
+	result[0] = key[0]
+	for i := 1; i < 7; i++ {
+		result[i] = key[i-1]<<(8-uint(i)) | key[i]>>uint(i)
+	}
+	result[7] = key[6] << 1
+
+	return result
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	return b, err
+}
@@ -0,0 +1,133 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func init(){
+	fmt.Println("+++++ init chap_test +++++")
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Test vectors of RFC 2433 appendix B.2 and RFC 2759 section 9.2
+func TestMSCHAPVectors(t *testing.T) {
+
+	if result := hex.EncodeToString(ntPasswordHash("MyPw")); result != "fc156af7edcd6c0edde3337d427f4eac" {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", result, "fc156af7edcd6c0edde3337d427f4eac")
+	}
+
+	data, err := MSCHAPData(1, decodeHex(t, "102db5df085d3041"), "MyPw")
+
+	if err != nil || hex.EncodeToString(data[33:57]) != "4e9d3c8f9cfd385d5bf4d3246791956ca4c351ab409a3d61" {
+		t.Errorf("Result was incorrect, got: %x (%v), want: %s.", data, err, "4e9d3c8f9cfd385d5bf4d3246791956ca4c351ab409a3d61")
+	}
+
+	challenge := decodeHex(t, "5b5d7c7d7b3f2f3e3c2c602132262628")
+	peerChallenge := decodeHex(t, "21402324255e262a28295f2b3a337c7e")
+
+	data, err = MSCHAPv2Data(1, challenge, peerChallenge, "User", "clientPass")
+
+	if err != nil || hex.EncodeToString(data[41:65]) != "82309ecd8d708b5ea08faa3981cd83544233114a3d85d6df" {
+		t.Errorf("Result was incorrect, got: %x (%v), want: %s.", data, err, "82309ecd8d708b5ea08faa3981cd83544233114a3d85d6df")
+	}
+
+	if len(data) != 66 || !bytes.Equal(data[17:33], peerChallenge) {
+		t.Errorf("Result was incorrect, got: %x, want the PPP id, the challenge and a 49 bytes response.", data)
+	}
+}
+
+func TestCHAPData(t *testing.T) {
+
+	data := CHAPData(7, []byte("challenge"), "password")
+
+	if len(data) != 1+9+16 || data[0] != 7 || string(data[1:10]) != "challenge" {
+		t.Errorf("Result was incorrect, got: %x, want the PPP id, the challenge and the MD5 response.", data)
+	}
+
+	if !VerifyCHAP(data, "password") || VerifyCHAP(data, "wrong") {
+		t.Errorf("Result was incorrect, CHAP response not verified against the password")
+	}
+}
+
+// chapHandler passes the authentications whose data matches the password
+type chapHandler struct {
+	testHandler
+	password string
+}
+
+func (h chapHandler) HandleAuthenStart(ctx context.Context, a *AuthenStart, s *ServerSession) *AuthenReply {
+
+	passed := false
+
+	switch a.AuthenType {
+	case AuthenTypeCHAP:
+		passed = VerifyCHAP(a.Data, h.password)
+	case AuthenTypeMSCHAP:
+		passed = VerifyMSCHAP(a.Data, h.password)
+	case AuthenTypeMSCHAPv2:
+		passed = VerifyMSCHAPv2(a.Data, a.User, h.password)
+	}
+
+	if passed {
+		return &AuthenReply{Status: AuthenStatusPass}
+	}
+	return &AuthenReply{Status: AuthenStatusFail}
+}
+
+func TestCHAPAuthentication(t *testing.T) {
+
+	info := startTestServer(t, chapHandler{password: "password"})
+	client := NewFailoverClient([]TacacsInfo{info})
+
+	for _, authenType := range []uint8{AuthenTypeCHAP, AuthenTypeMSCHAP, AuthenTypeMSCHAPv2} {
+		for password, correct := range map[string]uint8{"password": AuthenStatusPass, "wrong": AuthenStatusFail} {
+
+			data, err := AuthenData(authenType, "admin", password)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reply, _, err := client.SendAuthenStart(context.Background(), &AuthenStart{
+				Action:        AuthenActionLogin,
+				AuthenType:    authenType,
+				AuthenService: AuthenServicePPP,
+				User:          "admin",
+				Data:          data,
+			})
+
+			if err != nil || reply.Status != correct {
+				t.Errorf("Result was incorrect for type %d with %s, got: %+v (%v), want: %d.", authenType, password, reply, err, correct)
+			}
+		}
+	}
+}
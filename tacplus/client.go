@@ -2,10 +2,12 @@ package tacplus
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/binary"
 	"errors"
 	"net"
 	"sync"
+	"time"
 )
 
 // ClientSession is a TACACS+ client session.
@@ -82,6 +84,10 @@ type Client struct {
 	// Optional DialContext function used to create the network connection.
 	DialContext func(ctx context.Context, net, addr string) (net.Conn, error)
 
+	// Optional TLS configuration. When set the connection is a TLS connection
+	// and the packet bodies are not obfuscated with the shared secret.
+	TLSConfig *tls.Config
+
 	mu   sync.Mutex // protects access to conn
 	conn *conn      // current cached mux connection
 }
@@ -99,15 +105,41 @@ func (c *Client) Close() {
 var zeroDialer net.Dialer
 
 func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	var nc net.Conn
+	var err error
 	if c.DialContext != nil {
-		return c.DialContext(ctx, "tcp", c.Addr)
+		nc, err = c.DialContext(ctx, "tcp", c.Addr)
+	} else {
+		nc, err = zeroDialer.DialContext(ctx, "tcp", c.Addr)
+	}
+	if err != nil || c.TLSConfig == nil {
+		return nc, err
+	}
+	return tlsHandshake(ctx, nc, c.TLSConfig)
+}
+
+// tlsHandshake runs the client TLS handshake on nc within the deadline of ctx.
+func tlsHandshake(ctx context.Context, nc net.Conn, cfg *tls.Config) (net.Conn, error) {
+	tc := tls.Client(nc, cfg)
+	if deadline, ok := ctx.Deadline(); ok {
+		tc.SetDeadline(deadline)
+		defer tc.SetDeadline(time.Time{})
 	}
-	return zeroDialer.DialContext(ctx, "tcp", c.Addr)
+	if err := tc.Handshake(); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return tc, nil
 }
 
+// TestConnection dials the server and closes the probe connection.
 func (c *Client) TestConnection(ctx context.Context) bool {
-	_, err := c.dial(ctx)
-	return err == nil
+	nc, err := c.dial(ctx)
+	if err != nil {
+		return false
+	}
+	nc.Close()
+	return true
 }
 
 func (c *Client) newSession(ctx context.Context) (*session, error) {
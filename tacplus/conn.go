@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/md5"
 	"crypto/rand"
+	"crypto/tls"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -32,6 +33,7 @@ const (
 	hdrBodyLen = 8
 
 	// Packet header flags
+	hdrFlagUnencrypted   = 0x01 // packet body not obfuscated, set on TLS connections
 	hdrFlagSingleConnect = 0x04 // multiplex requests over a single connection
 )
 
@@ -43,6 +45,7 @@ var (
 	errSessionNotFound  = errors.New("session not found or timed out")
 	errUnexpectedEOF    = errors.New("unexpected EOF")
 	errPacketQueueFull  = errors.New("packet queue full")
+	errObfuscatedTLS    = errors.New("obfuscated packet on TLS connection")
 )
 
 // doneContext allows a done channel to be used as a context.Context
@@ -180,6 +183,14 @@ func (s *session) readPacket(ctx context.Context) ([]byte, error) {
 		return p, errInvalidSeqNo
 	}
 
+	// TLS replaces the obfuscation, the packets must be sent in the clear
+	if s.c.tls {
+		if p[hdrFlags]&hdrFlagUnencrypted == 0 {
+			return p, errObfuscatedTLS
+		}
+		return p, nil
+	}
+
 	crypt(p, s.c.Secret)
 	return p, nil
 }
@@ -197,7 +208,11 @@ func (s *session) writePacket(ctx context.Context, p []byte) error {
 
 	// set body size
 	binary.BigEndian.PutUint32(p[hdrBodyLen:], uint32(len(p)-hdrLen))
-	crypt(p, s.c.Secret)
+	if s.c.tls {
+		p[hdrFlags] |= hdrFlagUnencrypted
+	} else {
+		crypt(p, s.c.Secret)
+	}
 
 	wr := writeRequest{p: p, ec: make(chan error, 1)}
 	if deadline, ok := ctx.Deadline(); ok {
@@ -267,6 +282,10 @@ type ConnConfig struct {
 	ReadTimeout  time.Duration // Maximum time to read a packet (not including waiting for first byte)
 	WriteTimeout time.Duration // Maximum time to write a packet
 
+	// Optional function called with the multiplexing status negotiated on the
+	// first packet of a Mux connection.
+	MuxNegotiated func(mux bool)
+
 	// Optional function to log errors. If not defined log.Print will be used.
 	Log func(v ...interface{})
 }
@@ -289,6 +308,7 @@ type conn struct {
 	sess     map[uint32]*session // session store
 	parity   uint8               // parity of sequence number for incoming packets
 	mux      bool                // connection multiplexing status
+	tls      bool                // TLS transport, packet bodies are not obfuscated
 	checkMux bool                // connection multiplexing to be negotatied
 	idleT    *time.Timer         // idle timer
 
@@ -496,6 +516,9 @@ func (c *conn) processPacket(p []byte) {
 	if c.checkMux {
 		c.mux = p[hdrFlags]&hdrFlagSingleConnect > 0
 		c.checkMux = false
+		if c.MuxNegotiated != nil {
+			c.MuxNegotiated(c.mux)
+		}
 	}
 
 	id := binary.BigEndian.Uint32(p[hdrID:])
@@ -613,6 +636,7 @@ func newConn(nc net.Conn, h func(*session), cfg ConnConfig) *conn {
 		handle:     h,
 		ConnConfig: cfg,
 	}
+	_, c.tls = nc.(*tls.Conn)
 	if c.handle == nil {
 		// client connection
 		c.sessReq = make(chan sessRequest)
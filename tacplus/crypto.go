@@ -0,0 +1,111 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Path of the master key the TACACS+ passkeys are encrypted with
+var MasterKeyPath = "/etc/sonic/enc_master_key"
+
+const (
+	opensslSaltHeader = "Salted__"
+	opensslSaltSize   = 8
+	pbkdf2Iterations  = 10000 // Default of openssl enc -pbkdf2
+)
+
+/*Decrypts a passkey encrypted with the master key, as SONiC does with
+echo <passkey> | openssl enc -aes-256-cbc -salt -base64 -pbkdf2 -pass file:<master key>
+*/
+func DecryptPasskey(encrypted string) (string, error) {
+
+	masterKey, err := readMasterKey(MasterKeyPath)
+
+	if err != nil {
+		return "", err
+	}
+
+	return decryptOpenSSL(encrypted, masterKey)
+}
+
+// Returns the first line of the master key file, the password openssl reads with -pass file:
+func readMasterKey(path string) ([]byte, error) {
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[:i]
+	}
+
+	return data, nil
+}
+
+// Decrypts the base64 output of openssl enc -aes-256-cbc -salt -pbkdf2, the key
+// and iv are derived from the password and salt with PBKDF2-HMAC-SHA256
+func decryptOpenSSL(encrypted string, password []byte) (string, error) {
+
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encrypted), ""))
+
+	if err != nil {
+		return "", errors.New("Invalid encrypted passkey encoding")
+	}
+
+	header := len(opensslSaltHeader) + opensslSaltSize
+
+	if len(data) < header+aes.BlockSize || !bytes.HasPrefix(data, []byte(opensslSaltHeader)) || (len(data)-header)%aes.BlockSize != 0 {
+		return "", errors.New("Invalid encrypted passkey format")
+	}
+
+	salt := data[len(opensslSaltHeader):header]
+	key := pbkdf2.Key(password, salt, pbkdf2Iterations, 32+aes.BlockSize, sha256.New)
+
+	block, err := aes.NewCipher(key[:32])
+
+	if err != nil {
+		return "", err
+	}
+
+	plain := make([]byte, len(data)-header)
+	cipher.NewCBCDecrypter(block, key[32:]).CryptBlocks(plain, data[header:])
+
+	// Remove the PKCS#7 padding, a wrong master key gives an invalid one
+	padding := int(plain[len(plain)-1])
+
+	if padding == 0 || padding > aes.BlockSize || !bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return "", errors.New("Unable to decrypt passkey, bad master key")
+	}
+
+	// The passkey is encrypted with the line feed of echo
+	return strings.TrimSuffix(string(plain[:len(plain)-padding]), "\n"), nil
+}
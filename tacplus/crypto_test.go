@@ -0,0 +1,82 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func init(){
+	fmt.Println("+++++ init crypto_test +++++")
+}
+
+// Master key file with a second line openssl ignores
+const testMasterKey = "MasterKey123\nsecondline\n"
+
+func TestDecryptPasskey(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "tacplus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	savedPath := MasterKeyPath
+	defer func() { MasterKeyPath = savedPath }()
+
+	MasterKeyPath = filepath.Join(dir, "enc_master_key")
+	ioutil.WriteFile(MasterKeyPath, []byte(testMasterKey), 0600)
+
+	// Generated with echo <passkey> | openssl enc -aes-256-cbc -salt -base64 -pbkdf2 -pass file:<master key>
+	vectors := map[string]string{
+		"U2FsdGVkX18xnv2imQqnO+Xlb73Qy1+Anbej6BEMIDM=":                                                                     "tacacs-secret",
+		"U2FsdGVkX1911D6nDq3w+WG9GvVOXtwU9g5jUW+STy2N3S+YTemIZhZyYC2RDJec\n/JmVTJGK2Cn0C/bV7F96qRk56TcXdvhuBpOeQG4Nkn8=\n": "p@ss with spaces and a longer secret 0123456789",
+		"U2FsdGVkX1/pq/X7AX7txJO1wp503JgyHhnh+eOkCj8=":                                                                     "nonewline",
+	}
+
+	for encrypted, correct := range vectors {
+		result, err := DecryptPasskey(encrypted)
+		if err != nil || result != correct {
+			t.Errorf("Result was incorrect, got: %s (%v), want: %s.", result, err, correct)
+		}
+	}
+
+	ioutil.WriteFile(MasterKeyPath, []byte("WrongKey\n"), 0600)
+
+	if result, err := DecryptPasskey("U2FsdGVkX18xnv2imQqnO+Xlb73Qy1+Anbej6BEMIDM="); err == nil && result == "tacacs-secret" {
+		t.Errorf("Result was incorrect, passkey decrypted with a wrong master key")
+	}
+
+	for _, invalid := range []string{"not base64!", "dGFjYWNz", "U2FsdGVkX18xnv2imQqnO+Xlb73Qy1+A"} {
+		if _, err := DecryptPasskey(invalid); err == nil {
+			t.Errorf("Result was incorrect, no error for %s", invalid)
+		}
+	}
+
+	MasterKeyPath = filepath.Join(dir, "missing")
+
+	if _, err := DecryptPasskey("U2FsdGVkX18xnv2imQqnO+Xlb73Qy1+Anbej6BEMIDM="); err == nil {
+		t.Errorf("Result was incorrect, no error without master key")
+	}
+}
@@ -0,0 +1,253 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Time a failing server is skipped, doubled on each consecutive failure up to MaxServerBackoff
+var ServerBackoff = 30 * time.Second
+
+// Longest time a failing server is skipped
+var MaxServerBackoff = 10 * time.Minute
+
+var errNoServer = errors.New("No TACACS+ server configured")
+
+// serverHealth is the failure state of a server, shared by the clients of all sessions
+type serverHealth struct {
+	failures int       // Consecutive failures
+	until    time.Time // End of the backoff
+}
+
+var healthLock sync.Mutex
+var healthStates = map[string]*serverHealth{}
+
+// markFailed starts or extends the backoff of a server
+func markFailed(addr string) time.Duration {
+
+	healthLock.Lock()
+	defer healthLock.Unlock()
+
+	state, ok := healthStates[addr]
+	if !ok {
+		state = &serverHealth{}
+		healthStates[addr] = state
+	}
+
+	backoff := ServerBackoff
+	for i := 0; i < state.failures && backoff < MaxServerBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > MaxServerBackoff {
+		backoff = MaxServerBackoff
+	}
+
+	state.failures++
+	state.until = time.Now().Add(backoff)
+
+	return backoff
+}
+
+// markHealthy ends the backoff of a server
+func markHealthy(addr string) {
+	healthLock.Lock()
+	delete(healthStates, addr)
+	healthLock.Unlock()
+}
+
+// backoffEnd returns the end of the backoff of a server, zero when healthy
+func backoffEnd(addr string) time.Time {
+	healthLock.Lock()
+	defer healthLock.Unlock()
+	if state, ok := healthStates[addr]; ok && time.Now().Before(state.until) {
+		return state.until
+	}
+	return time.Time{}
+}
+
+// failoverServer is a server of a FailoverClient, its shared client is looked
+// up in the pool for each request
+type failoverServer struct {
+	info TacacsInfo
+	addr string
+}
+
+/*FailoverClient sends each request to the servers in priority order until one answers.
+A server failing to answer, or answering with an error status, is skipped by all the clients during a backoff.
+Servers in backoff are still tried last, when no healthy server answered
+*/
+type FailoverClient struct {
+	servers []failoverServer
+}
+
+// Creates a client of the servers, in the given priority order, with the
+// shared clients of the pool
+func NewFailoverClient(servers []TacacsInfo) *FailoverClient {
+
+	f := &FailoverClient{}
+
+	for i := range servers {
+		SharedClient(&servers[i])
+		f.servers = append(f.servers, failoverServer{info: servers[i], addr: poolAddr(&servers[i])})
+	}
+
+	return f
+}
+
+// Returns the configuration of the servers in priority order
+func (f *FailoverClient) Servers() []TacacsInfo {
+	servers := make([]TacacsInfo, len(f.servers))
+	for i, server := range f.servers {
+		servers[i] = server.info
+	}
+	return servers
+}
+
+// order returns the servers to try, the healthy ones by priority then the others
+// by end of backoff
+func (f *FailoverClient) order() []failoverServer {
+
+	healthy := []failoverServer{}
+	failing := []failoverServer{}
+	ends := map[string]time.Time{}
+
+	for _, server := range f.servers {
+		if end := backoffEnd(server.addr); end.IsZero() {
+			healthy = append(healthy, server)
+		} else {
+			ends[server.addr] = end
+			failing = append(failing, server)
+		}
+	}
+
+	sort.SliceStable(failing, func(i, j int) bool {
+		return ends[failing[i].addr].Before(ends[failing[j].addr])
+	})
+
+	return append(healthy, failing...)
+}
+
+// do runs send with the servers until one succeeds, each attempt is limited by
+// the timeout of the server
+func (f *FailoverClient) do(ctx context.Context, send func(ctx context.Context, c *Client) error) error {
+
+	err := errNoServer
+
+	for _, server := range f.order() {
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if server.info.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, time.Duration(server.info.Timeout)*time.Second)
+		}
+
+		err = send(attemptCtx, currentClient(&server.info))
+		cancel()
+
+		if err == nil {
+			markHealthy(server.addr)
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return err
+		}
+
+		backoff := markFailed(server.addr)
+		glog.Warningf("TACACS+ server (%s) failed, skipped for %s: %v", server.addr, backoff, err)
+	}
+
+	return err
+}
+
+// serverError is an error status replied by a server
+type serverError string
+
+func (e serverError) Error() string {
+	return "TACACS+ server error: " + string(e)
+}
+
+// SendAuthenStart sends an AuthenStart to the first server answering. The
+// ClientSession of an interactive authentication continues with that server.
+func (f *FailoverClient) SendAuthenStart(ctx context.Context, as *AuthenStart) (*AuthenReply, *ClientSession, error) {
+
+	var reply *AuthenReply
+	var session *ClientSession
+
+	err := f.do(ctx, func(ctx context.Context, c *Client) error {
+		r, s, err := c.SendAuthenStart(ctx, as)
+		if err != nil {
+			return err
+		}
+		if r.Status == AuthenStatusError {
+			return serverError(r.ServerMsg)
+		}
+		reply, session = r, s
+		return nil
+	})
+
+	return reply, session, err
+}
+
+// SendAuthorRequest sends an AuthorRequest to the first server answering
+func (f *FailoverClient) SendAuthorRequest(ctx context.Context, req *AuthorRequest) (*AuthorResponse, error) {
+
+	var response *AuthorResponse
+
+	err := f.do(ctx, func(ctx context.Context, c *Client) error {
+		r, err := c.SendAuthorRequest(ctx, req)
+		if err != nil {
+			return err
+		}
+		if r.Status == AuthorStatusError {
+			return serverError(r.ServerMsg)
+		}
+		response = r
+		return nil
+	})
+
+	return response, err
+}
+
+// SendAcctRequest sends an AcctRequest to the first server answering
+func (f *FailoverClient) SendAcctRequest(ctx context.Context, req *AcctRequest) (*AcctReply, error) {
+
+	var reply *AcctReply
+
+	err := f.do(ctx, func(ctx context.Context, c *Client) error {
+		r, err := c.SendAcctRequest(ctx, req)
+		if err != nil {
+			return err
+		}
+		if r.Status == AcctStatusError {
+			return serverError(r.ServerMsg)
+		}
+		reply = r
+		return nil
+	})
+
+	return reply, err
+}
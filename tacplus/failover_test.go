@@ -0,0 +1,175 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func init(){
+	fmt.Println("+++++ init failover_test +++++")
+}
+
+// testHandler allows every authorization, or replies with an error status
+type testHandler struct {
+	status uint8
+}
+
+func (h testHandler) HandleAuthenStart(ctx context.Context, a *AuthenStart, s *ServerSession) *AuthenReply {
+	return &AuthenReply{Status: AuthenStatusPass}
+}
+
+func (h testHandler) HandleAuthorRequest(ctx context.Context, a *AuthorRequest, s *ServerSession) *AuthorResponse {
+	return &AuthorResponse{Status: h.status}
+}
+
+func (h testHandler) HandleAcctRequest(ctx context.Context, a *AcctRequest, s *ServerSession) *AcctReply {
+	return &AcctReply{Status: AcctStatusSuccess}
+}
+
+// startTestServer serves handler on a local port, a nil handler accepts the
+// connections without ever answering
+func startTestServer(t *testing.T, handler RequestHandler) TacacsInfo {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(nc net.Conn) {
+		(&ServerConnHandler{Handler: handler, ConnConfig: ConnConfig{Secret: []byte("secret")}}).Serve(nc)
+	}
+	if handler == nil {
+		serve = func(nc net.Conn) {}
+	}
+
+	go (&Server{ServeConn: serve}).Serve(l)
+
+	addr := l.Addr().(*net.TCPAddr)
+
+	return TacacsInfo{IP: addr.IP.String(), Port: addr.Port, Password: "secret", Timeout: 1}
+}
+
+// closedServer returns the configuration of a server refusing connections
+func closedServer(t *testing.T) TacacsInfo {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	addr := l.Addr().(*net.TCPAddr)
+
+	return TacacsInfo{IP: addr.IP.String(), Port: addr.Port, Password: "secret", Timeout: 1}
+}
+
+// totalConns returns the connections opened to the servers of f
+func totalConns(f *FailoverClient) string {
+
+	counts := map[string]uint64{}
+	for _, stats := range PoolStats() {
+		counts[stats.Addr] = stats.TotalConns
+	}
+
+	result := []uint64{}
+	for _, server := range f.servers {
+		result = append(result, counts[server.addr])
+	}
+
+	return fmt.Sprint(result)
+}
+
+func TestFailoverClient(t *testing.T) {
+
+	down := closedServer(t)
+	silent := startTestServer(t, nil)
+	failing := startTestServer(t, testHandler{status: AuthorStatusError})
+	up := startTestServer(t, testHandler{status: AuthorStatusPassAdd})
+
+	defer func() {
+		for _, info := range []TacacsInfo{down, silent, failing, up} {
+			markHealthy(info.IP + ":" + strconv.Itoa(info.Port))
+		}
+	}()
+
+	f := NewFailoverClient([]TacacsInfo{down, silent, failing, up})
+
+	start := time.Now()
+
+	response, err := f.SendAuthorRequest(context.Background(), &AuthorRequest{User: "admin"})
+
+	if err != nil || response.Status != AuthorStatusPassAdd {
+		t.Fatalf("Result was incorrect, got: %+v (%v), want: %d.", response, err, AuthorStatusPassAdd)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 3*time.Second {
+		t.Errorf("Result was incorrect, got: %s, want the 1s timeout of the silent server.", elapsed)
+	}
+
+	// The failing servers are skipped during their backoff
+	if _, err := f.SendAuthorRequest(context.Background(), &AuthorRequest{User: "admin"}); err != nil {
+		t.Errorf("Result was incorrect, got: %v, want: no error.", err)
+	}
+
+	if result := totalConns(f); result != "[0 1 1 2]" {
+		t.Errorf("Result was incorrect, got: %s, want: [0 1 1 2].", result)
+	}
+
+	order := f.order()
+
+	if order[0].info != up || order[1].info != down {
+		t.Errorf("Result was incorrect, got: %v first, want: %v then %v.", order[0].info, up, down)
+	}
+}
+
+func TestServerBackoff(t *testing.T) {
+
+	savedBackoff, savedMax := ServerBackoff, MaxServerBackoff
+	defer func() { ServerBackoff, MaxServerBackoff = savedBackoff, savedMax }()
+	defer markHealthy("test:49")
+
+	ServerBackoff = time.Second
+	MaxServerBackoff = 5 * time.Second
+
+	backoffs := []time.Duration{}
+	for i := 0; i < 5; i++ {
+		backoffs = append(backoffs, markFailed("test:49"))
+	}
+
+	if fmt.Sprint(backoffs) != "[1s 2s 4s 5s 5s]" {
+		t.Errorf("Result was incorrect, got: %v, want: [1s 2s 4s 5s 5s].", backoffs)
+	}
+
+	if backoffEnd("test:49").IsZero() {
+		t.Errorf("Result was incorrect, server not in backoff")
+	}
+
+	markHealthy("test:49")
+
+	if !backoffEnd("test:49").IsZero() {
+		t.Errorf("Result was incorrect, server still in backoff")
+	}
+}
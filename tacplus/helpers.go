@@ -25,7 +25,6 @@ import (
 	"errors"
 	"math/rand"
 	"net"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
@@ -41,6 +40,8 @@ type TacacsInfo struct {
 	Password string
 	Timeout  int
 	AuthType string
+	Source   string // Source interface or address of the connections, TACPLUS|global src_intf
+	TLS      TLSInfo
 	index    int
 }
 
@@ -73,6 +74,11 @@ func IsTacacsAAAEnabled() bool {
 	return false
 }
 
+// Returns the fields of an AAA table entry, e.g. AAA|authentication
+func GetAAAConfig(key string) (map[string]string, error) {
+	return redisClient.HGetAll("AAA|" + key).Result()
+}
+
 func IsTacacsEnabled() bool {
 	tacKeys, err := redisClient.Keys("TACPLUS_SERVER|*").Result()
 	if err != nil {
@@ -88,6 +94,7 @@ func GetTacacsInfo() (PriorityQueue, error) {
 	globalTacacsTimeout := 5
 	globalTacacsPass := ""
 	globalTacacsAuthType := "pap"
+	globalTacacsSource := ""
 
 	tacacsGlobal, err := redisClient.HGetAll("TACPLUS|global").Result()
 
@@ -99,16 +106,16 @@ func GetTacacsInfo() (PriorityQueue, error) {
 
 				glog.Info("Tacacs key is encrypted, decrypting ...")
 
-				decrypted, err := exec.Command("bash", "-c" , "echo " + pass + " | " + "openssl enc -d -aes-256-cbc -base64 -pbkdf2 -pass file:/etc/sonic/enc_master_key").Output()
+				decrypted, err := DecryptPasskey(pass)
 
 				if err != nil {
-					glog.Infof("Unable execute decrypt openssl command")
+					glog.Errorf("Unable to decrypt tacacs key: %v", err)
 					return nil, errors.New("Cannot get tacacs server info")
 				}
 
 				glog.Infof("Decryption success")
 
-				globalTacacsPass = string(decrypted)
+				globalTacacsPass = decrypted
 
 			}else{
 				globalTacacsPass = pass
@@ -123,6 +130,8 @@ func GetTacacsInfo() (PriorityQueue, error) {
 		if timeout, ok := tacacsGlobal["timeout"]; ok {
 			globalTacacsTimeout, _ = strconv.Atoi(timeout)
 		}
+
+		globalTacacsSource = tacacsGlobal["src_intf"]
 	}
 
 	tacKeys, err := redisClient.Keys("TACPLUS_SERVER|*").Result()
@@ -142,7 +151,14 @@ func GetTacacsInfo() (PriorityQueue, error) {
 		}
 
 		tacIp := strings.Split(key, "|")[1]
-		tacPort, _ := strconv.Atoi(serverData["tcp_port"])
+		tacTLS := getTLSInfo(serverData)
+		tacPort := DefaultPort
+		if tacTLS.Enabled {
+			tacPort = DefaultTLSPort
+		}
+		if port, ok := serverData["tcp_port"]; ok {
+			tacPort, _ = strconv.Atoi(port)
+		}
 		tacPriority, _ := strconv.Atoi(serverData["priority"])
 		tacTimeout := globalTacacsTimeout
 		tacPassword := globalTacacsPass
@@ -157,16 +173,16 @@ func GetTacacsInfo() (PriorityQueue, error) {
 
 				glog.Info("Tacacs key is encrypted, decrypting ...")
 
-				decrypted, err := exec.Command("bash", "-c" , "echo " + pass + " | " + "openssl enc -d -aes-256-cbc -base64 -pbkdf2 -pass file:/etc/sonic/enc_master_key").Output()
+				decrypted, err := DecryptPasskey(pass)
 
 				if err != nil {
-					glog.Infof("Unable execute decrypt openssl command")
+					glog.Errorf("Unable to decrypt tacacs key: %v", err)
 					return nil, errors.New("Cannot get tacacs server info")
 				}
 
 				glog.Infof("Decryption success")
 
-				tacPassword = string(decrypted)
+				tacPassword = decrypted
 
 			}else{
 				tacPassword = pass
@@ -188,6 +204,8 @@ func GetTacacsInfo() (PriorityQueue, error) {
 			Timeout:  tacTimeout,
 			Password: tacPassword,
 			AuthType: tacAuthType,
+			Source:   globalTacacsSource,
+			TLS:      tacTLS,
 		}
 	}
 
@@ -202,37 +220,124 @@ func GenerateRandomInt() int {
 	return rand.Intn(1000000-1000+1) + 1000 // Generate random number between 1000 - 1000000
 }
 
+// Returns the first global address of the host, IPv4 first then IPv6
 func GetLocalIP() string {
 	addrs, err := net.InterfaceAddrs()
 	if err != nil {
 		return ""
 	}
+	if ip := globalIP(addrs, false); ip != nil {
+		return ip.String()
+	}
+	if ip := globalIP(addrs, true); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// Returns the IP address of a remote address, without port or IPv6 zone
+func RemoteIP(addr net.Addr) string {
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		return tcpAddr.IP.String()
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return strings.Split(host, "%")[0]
+}
+
+/*Returns the address to connect to server from, source being an address or the name of an interface.
+The address of an interface is its first global address of the family of server
+*/
+func SourceIP(source string, server net.IP) (net.IP, error) {
+
+	if ip := net.ParseIP(source); ip != nil {
+		return ip, nil
+	}
+
+	intf, err := net.InterfaceByName(source)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := intf.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	ip := globalIP(addrs, server != nil && server.To4() == nil)
+	if ip == nil {
+		return nil, errors.New("No address on source interface " + source)
+	}
+
+	return ip, nil
+}
+
+// globalIP returns the first global unicast IPv4, or IPv6, address of addrs
+func globalIP(addrs []net.Addr, ipv6 bool) net.IP {
 	for _, address := range addrs {
-		// check the address type and if it is not a loopback the display it
-		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			if ipnet.IP.To4() != nil {
-				return ipnet.IP.String()
+		if ipnet, ok := address.(*net.IPNet); ok && ipnet.IP.IsGlobalUnicast() && (ipnet.IP.To4() == nil) == ipv6 {
+			return ipnet.IP
+		}
+	}
+	return nil
+}
+
+// dialFrom returns a dial function binding the connections to the source
+// address, resolved at each connection as the interface addresses may change
+func dialFrom(source string) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
+
+		dialer := net.Dialer{}
+
+		if source != "" {
+			host, _, _ := net.SplitHostPort(addr)
+			ip, err := SourceIP(source, net.ParseIP(host))
+			if err != nil {
+				return nil, err
 			}
+			dialer.LocalAddr = &net.TCPAddr{IP: ip}
 		}
+
+		return dialer.DialContext(ctx, network, addr)
 	}
-	return ""
 }
 
-// Creates a connection from a specific configuration
+/*Creates a connection from a specific configuration, packets are limited by the server timeout.
+A TLS server with an invalid TLS configuration gets a client failing to connect
+*/
 func CreateClientFromInfo(info *TacacsInfo) *Client {
-	return &Client{
+	timeout := time.Duration(info.Timeout) * time.Second
+	client := &Client{
 		Addr: info.IP + ":" + strconv.Itoa(info.Port),
 		ConnConfig: ConnConfig{
-			Secret: []byte(info.Password),
-			Mux:    false,
+			Secret:       []byte(info.Password),
+			Mux:          false,
+			ReadTimeout:  timeout,
+			WriteTimeout: timeout,
 		},
+		DialContext: dialFrom(info.Source),
+	}
+
+	if info.TLS.Enabled {
+		config, err := info.TLS.clientConfig(info.IP)
+		if err != nil {
+			glog.Errorf("Invalid TLS configuration of tacacs server (%s): %v", info.IP, err)
+			client.DialContext = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+				return nil, err
+			}
+		}
+		client.TLSConfig = config
 	}
+
+	return client
 }
 
 /*Creates a client by reading the conifg db for tacacs configurations
-Returns a reference to a newly created client with the highest priority and an established connection
+Returns a client of all the servers in priority order and the configuration of the highest priority one
 */
-func CreateClient(context context.Context) (*Client, TacacsInfo, error) {
+func CreateClient(context context.Context) (*FailoverClient, TacacsInfo, error) {
 
 	queue, err := GetTacacsInfo()
 
@@ -240,24 +345,13 @@ func CreateClient(context context.Context) (*Client, TacacsInfo, error) {
 		return nil, TacacsInfo{}, errors.New("No tacacs configuration found")
 	}
 
-	for queue.Len() > 0 {
+	servers := []TacacsInfo{}
 
+	for queue.Len() > 0 {
 		info := heap.Pop(&queue).(*TacacsInfo)
-
-		client := CreateClientFromInfo(info)
-
-		glog.Infof("Found server (%s) in db, testing connection", info.IP)
-
-		connected := client.TestConnection(context)
-
-		if connected {
-			glog.Infof("Successful connection to server (%s)", info.IP)
-			return client, *info, nil
-		}
-
-		glog.Warningf("Unable to connect to server (%s) - skipping", info.IP)
-
+		glog.Infof("Found server (%s) in db", info.IP)
+		servers = append(servers, *info)
 	}
 
-	return nil, TacacsInfo{}, errors.New("Unable to connect to any TACACS servers")
+	return NewFailoverClient(servers), servers[0], nil
 }
@@ -0,0 +1,102 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func init(){
+	fmt.Println("+++++ init helpers_test +++++")
+}
+
+func TestRemoteIP(t *testing.T) {
+
+	tests := []struct {
+		addr    net.Addr
+		correct string
+	}{
+		{&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000}, "10.0.0.1"},
+		{&net.TCPAddr{IP: net.ParseIP("::ffff:10.0.0.1"), Port: 50000}, "10.0.0.1"},
+		{&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 50000, Zone: "eth0"}, "2001:db8::1"},
+		{&net.UDPAddr{IP: net.ParseIP("fe80::1"), Port: 50000, Zone: "eth0"}, "fe80::1"},
+	}
+
+	for _, test := range tests {
+		if result := RemoteIP(test.addr); result != test.correct {
+			t.Errorf("Result was incorrect, got: %s, want: %s.", result, test.correct)
+		}
+	}
+}
+
+func TestGlobalIP(t *testing.T) {
+
+	addrs := []net.Addr{}
+	for _, cidr := range []string{"127.0.0.1/8", "fe80::1/64", "2001:db8::1/64", "10.0.0.1/24"} {
+		ip, ipnet, _ := net.ParseCIDR(cidr)
+		ipnet.IP = ip
+		addrs = append(addrs, ipnet)
+	}
+
+	if result := globalIP(addrs, false); result.String() != "10.0.0.1" {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", result, "10.0.0.1")
+	}
+
+	if result := globalIP(addrs, true); result.String() != "2001:db8::1" {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", result, "2001:db8::1")
+	}
+
+	if result := globalIP(addrs[:2], false); result != nil {
+		t.Errorf("Result was incorrect, got: %s, want: no address.", result)
+	}
+}
+
+// sourceHandler records the address the requests come from
+type sourceHandler struct {
+	testHandler
+	source chan string
+}
+
+func (h sourceHandler) HandleAuthorRequest(ctx context.Context, a *AuthorRequest, s *ServerSession) *AuthorResponse {
+	h.source <- RemoteIP(s.RemoteAddr())
+	return &AuthorResponse{Status: AuthorStatusPassAdd}
+}
+
+func TestSourceAddress(t *testing.T) {
+
+	handler := sourceHandler{source: make(chan string, 1)}
+	info := startTestServer(t, handler)
+	info.Source = "127.0.0.2"
+
+	if _, err := CreateClientFromInfo(&info).SendAuthorRequest(context.Background(), &AuthorRequest{User: "admin"}); err != nil {
+		t.Skipf("Source address 127.0.0.2 unavailable: %v", err)
+	}
+
+	if result := <-handler.source; result != "127.0.0.2" {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", result, "127.0.0.2")
+	}
+
+	if _, err := SourceIP("no-such-interface", nil); err == nil {
+		t.Errorf("Result was incorrect, got: no error, want: an unknown interface error.")
+	}
+}
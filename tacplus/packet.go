@@ -46,11 +46,12 @@ const (
 
 // AuthenType field values
 const (
-	AuthenTypeASCII  = 0x1
-	AuthenTypePAP    = 0x2
-	AuthenTypeCHAP   = 0x3
-	AuthenTypeARAP   = 0x4
-	AuthenTypeMSCHAP = 0x5
+	AuthenTypeASCII    = 0x1
+	AuthenTypePAP      = 0x2
+	AuthenTypeCHAP     = 0x3
+	AuthenTypeARAP     = 0x4
+	AuthenTypeMSCHAP   = 0x5
+	AuthenTypeMSCHAPv2 = 0x6
 )
 
 // AuthenStart Action field values
@@ -157,12 +158,12 @@ func (a *AuthenStart) version() uint8 {
 	switch a.Action {
 	case AuthenActionLogin:
 		switch a.AuthenType {
-		case AuthenTypePAP, AuthenTypeCHAP, AuthenTypeARAP, AuthenTypeMSCHAP:
+		case AuthenTypePAP, AuthenTypeCHAP, AuthenTypeARAP, AuthenTypeMSCHAP, AuthenTypeMSCHAPv2:
 			return verDefaultMinorOne
 		}
 	case AuthenActionSendAuth:
 		switch a.AuthenType {
-		case AuthenTypePAP, AuthenTypeCHAP, AuthenTypeMSCHAP:
+		case AuthenTypePAP, AuthenTypeCHAP, AuthenTypeMSCHAP, AuthenTypeMSCHAPv2:
 			return verDefaultMinorOne
 		}
 	}
@@ -0,0 +1,189 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Time before closing an idle multiplexed connection of the pool
+var PoolIdleTimeout = time.Minute
+
+// ServerStats are the connection counters of a pooled server
+type ServerStats struct {
+	Addr        string
+	OpenConns   int    // Connections currently open
+	TotalConns  uint64 // Connections opened since the client was created
+	Multiplexed bool   // The server accepted the single-connection mode
+}
+
+// pooledClient is the client of a server shared by all the sessions
+type pooledClient struct {
+	client *Client
+	info   TacacsInfo // Connection configuration the client was created with
+	dial   func(ctx context.Context, network string, addr string) (net.Conn, error)
+	stats  ServerStats
+}
+
+var poolLock sync.Mutex
+var pool = map[string]*pooledClient{}
+
+/*Returns the client of a server shared by all the sessions.
+The client requests the single-connection mode and keeps one connection open when the server accepts it,
+otherwise each request uses its own connection. A client is replaced when the server configuration changes
+*/
+func SharedClient(info *TacacsInfo) *Client {
+
+	addr := poolAddr(info)
+
+	poolLock.Lock()
+	defer poolLock.Unlock()
+
+	if p, ok := pool[addr]; ok {
+		if p.info == connInfo(info) {
+			return p.client
+		}
+		glog.Infof("TACACS+ server (%s) configuration changed, replacing its client", addr)
+		p.client.Close()
+	}
+
+	p := &pooledClient{info: connInfo(info), stats: ServerStats{Addr: addr}}
+
+	p.client = CreateClientFromInfo(info)
+	p.dial = p.client.DialContext
+	p.client.Addr = addr
+	p.client.ConnConfig.Mux = true
+	p.client.ConnConfig.IdleTimeout = PoolIdleTimeout
+	p.client.ConnConfig.MuxNegotiated = p.muxNegotiated
+	p.client.DialContext = p.countedDial
+
+	pool[addr] = p
+
+	return p.client
+}
+
+// currentClient returns the shared client of the server at the address of
+// info. The client of the current configuration is returned even when info is
+// an older configuration, so that a client replaced after a configuration
+// change is no longer used.
+func currentClient(info *TacacsInfo) *Client {
+
+	poolLock.Lock()
+	p, ok := pool[poolAddr(info)]
+	poolLock.Unlock()
+
+	if ok {
+		return p.client
+	}
+
+	return SharedClient(info)
+}
+
+// poolAddr returns the pool key of a server
+func poolAddr(info *TacacsInfo) string {
+	return net.JoinHostPort(info.IP, strconv.Itoa(info.Port))
+}
+
+// connInfo returns the fields of a server configuration its connections depend on
+func connInfo(info *TacacsInfo) TacacsInfo {
+	return TacacsInfo{IP: info.IP, Port: info.Port, Password: info.Password, Timeout: info.Timeout, Source: info.Source, TLS: info.TLS}
+}
+
+// Returns the connection counters of the pooled servers
+func PoolStats() []ServerStats {
+
+	poolLock.Lock()
+	defer poolLock.Unlock()
+
+	stats := []ServerStats{}
+	for _, p := range pool {
+		stats = append(stats, p.stats)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Addr < stats[j].Addr
+	})
+
+	return stats
+}
+
+// Logs the connection counters of the pooled servers every interval, until stop is closed
+func LogPoolStats(interval time.Duration, stop <-chan struct{}) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, stats := range PoolStats() {
+				glog.Infof("TACACS+ server (%s) connections open:%d total:%d multiplexed:%t", stats.Addr, stats.OpenConns, stats.TotalConns, stats.Multiplexed)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (p *pooledClient) muxNegotiated(mux bool) {
+	poolLock.Lock()
+	p.stats.Multiplexed = mux
+	poolLock.Unlock()
+}
+
+// countedDial opens a counted connection to the server
+func (p *pooledClient) countedDial(ctx context.Context, network string, addr string) (net.Conn, error) {
+
+	nc, err := p.dial(ctx, network, addr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	poolLock.Lock()
+	p.stats.OpenConns++
+	p.stats.TotalConns++
+	poolLock.Unlock()
+
+	return &pooledConn{Conn: nc, pool: p}, nil
+}
+
+// pooledConn decrements the open connections of its server when closed
+type pooledConn struct {
+	net.Conn
+	pool *pooledClient
+	once sync.Once
+}
+
+func (c *pooledConn) Close() error {
+	c.once.Do(func() {
+		poolLock.Lock()
+		c.pool.stats.OpenConns--
+		poolLock.Unlock()
+	})
+	return c.Conn.Close()
+}
@@ -0,0 +1,146 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func init(){
+	fmt.Println("+++++ init pool_test +++++")
+}
+
+// startMuxServer serves handler on a local port accepting the single-connection mode
+func startMuxServer(t *testing.T, handler RequestHandler) TacacsInfo {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	connHandler := &ServerConnHandler{Handler: handler, ConnConfig: ConnConfig{Secret: []byte("secret"), Mux: true}}
+
+	go (&Server{ServeConn: connHandler.Serve}).Serve(l)
+
+	addr := l.Addr().(*net.TCPAddr)
+
+	return TacacsInfo{IP: addr.IP.String(), Port: addr.Port, Password: "secret", Timeout: 1}
+}
+
+// serverStats returns the pool counters of a server
+func serverStats(info TacacsInfo) ServerStats {
+	addr := net.JoinHostPort(info.IP, fmt.Sprint(info.Port))
+	for _, stats := range PoolStats() {
+		if stats.Addr == addr {
+			return stats
+		}
+	}
+	return ServerStats{}
+}
+
+// waitOpenConns waits for the open connections of a server to drop to count
+func waitOpenConns(info TacacsInfo, count int) int {
+	for i := 0; i < 100 && serverStats(info).OpenConns != count; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return serverStats(info).OpenConns
+}
+
+func TestSharedClient(t *testing.T) {
+
+	mux := startMuxServer(t, testHandler{status: AuthorStatusPassAdd})
+	single := startTestServer(t, testHandler{status: AuthorStatusPassAdd})
+
+	for _, info := range []TacacsInfo{mux, single} {
+		client := SharedClient(&info)
+
+		if SharedClient(&info) != client {
+			t.Errorf("Result was incorrect, client of %s not shared", client.Addr)
+		}
+
+		for i := 0; i < 3; i++ {
+			if _, err := client.SendAuthorRequest(context.Background(), &AuthorRequest{User: "admin"}); err != nil {
+				t.Fatalf("Result was incorrect, got: %v, want: no error.", err)
+			}
+		}
+	}
+
+	if stats := serverStats(mux); !stats.Multiplexed || stats.TotalConns != 1 || waitOpenConns(mux, 1) != 1 {
+		t.Errorf("Result was incorrect, got: %+v, want: one multiplexed connection kept open.", stats)
+	}
+
+	if stats := serverStats(single); stats.Multiplexed || stats.TotalConns != 3 || waitOpenConns(single, 0) != 0 {
+		t.Errorf("Result was incorrect, got: %+v, want: three connections all closed.", stats)
+	}
+
+	// A new secret replaces the client and closes its connection
+	client := SharedClient(&mux)
+	changed := mux
+	changed.Password = "changed"
+
+	if SharedClient(&changed) == client {
+		t.Errorf("Result was incorrect, client kept after a secret change")
+	}
+
+	if stats := serverStats(mux); stats.TotalConns != 0 {
+		t.Errorf("Result was incorrect, got: %+v, want: new counters.", stats)
+	}
+}
+
+func TestConnectionProbe(t *testing.T) {
+
+	info := startTestServer(t, testHandler{status: AuthorStatusPassAdd})
+	client := SharedClient(&info)
+
+	if !client.TestConnection(context.Background()) {
+		t.Fatalf("Result was incorrect, server not reachable")
+	}
+
+	if open := waitOpenConns(info, 0); open != 0 {
+		t.Errorf("Result was incorrect, got: %d open connections, want: 0.", open)
+	}
+}
+
+func TestFailoverClientReplaced(t *testing.T) {
+
+	info := startMuxServer(t, testHandler{status: AuthorStatusPassAdd})
+	stale := NewFailoverClient([]TacacsInfo{info})
+
+	// A configuration change replaces the shared client used by stale
+	changed := info
+	changed.Timeout = 2
+	client := SharedClient(&changed)
+
+	if _, err := stale.SendAuthorRequest(context.Background(), &AuthorRequest{User: "admin"}); err != nil {
+		t.Fatalf("Result was incorrect, got: %v, want: no error.", err)
+	}
+
+	if SharedClient(&changed) != client {
+		t.Errorf("Result was incorrect, client of the new configuration replaced by an older client")
+	}
+
+	if stats := serverStats(info); stats.TotalConns != 1 {
+		t.Errorf("Result was incorrect, got: %+v, want: one connection of the new client.", stats)
+	}
+}
@@ -0,0 +1,135 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"strconv"
+)
+
+// TCP ports of the TACACS+ servers, with and without TLS
+const (
+	DefaultPort    = 49
+	DefaultTLSPort = 300
+)
+
+// TLSInfo is the TLS configuration of a server, the tls, tls_ca, tls_cert,
+// tls_key and tls_server_name fields of its TACPLUS_SERVER entry
+type TLSInfo struct {
+	Enabled    bool
+	CA         string // CA certificates file the server certificate is verified with, the system CAs when not set
+	Cert       string // Client certificate file, when the server verifies the clients
+	Key        string // Client private key file
+	ServerName string // Name verified in the server certificate, the server address when not set
+}
+
+// getTLSInfo returns the TLS configuration of a TACPLUS_SERVER entry
+func getTLSInfo(serverData map[string]string) TLSInfo {
+
+	enabled, _ := strconv.ParseBool(serverData["tls"])
+
+	return TLSInfo{
+		Enabled:    enabled,
+		CA:         serverData["tls_ca"],
+		Cert:       serverData["tls_cert"],
+		Key:        serverData["tls_key"],
+		ServerName: serverData["tls_server_name"],
+	}
+}
+
+// clientConfig returns the TLS 1.3 configuration of the connections to the
+// server at address
+func (t TLSInfo) clientConfig(address string) (*tls.Config, error) {
+
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS13,
+		ServerName: t.ServerName,
+	}
+
+	if config.ServerName == "" {
+		config.ServerName = address
+	}
+
+	if t.CA != "" {
+		pool, err := loadCertPool(t.CA)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	if t.Cert != "" || t.Key != "" {
+		cert, err := tls.LoadX509KeyPair(t.Cert, t.Key)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+/*Returns the TLS 1.3 configuration of a server with the certificate and key files.
+The client certificates are required and verified with the CA certificates file when one is given
+*/
+func ServerTLSConfig(certFile string, keyFile string, caFile string) (*tls.Config, error) {
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("No certificate found in " + path)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,192 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func init(){
+	fmt.Println("+++++ init tls_test +++++")
+}
+
+// writeCert writes a certificate and its key, signed by the CA of parent and
+// parentKey or self signed when parent is nil
+func writeCert(t *testing.T, dir string, name string, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".pem"), certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cert, _ := x509.ParseCertificate(der)
+
+	return cert, key
+}
+
+// writeTestPKI writes a CA, a server certificate for 127.0.0.1 and a client
+// certificate to dir, and a second CA which signed none of them
+func writeTestPKI(t *testing.T, dir string) {
+
+	notAfter := time.Now().Add(time.Hour)
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	ca, caKey := writeCert(t, dir, "ca", caTemplate, nil, nil)
+
+	caTemplate.Subject.CommonName = "other CA"
+	writeCert(t, dir, "other", caTemplate, nil, nil)
+
+	writeCert(t, dir, "server", &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "tacacs"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+
+	writeCert(t, dir, "client", &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "netconf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+}
+
+func TestTLSClient(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "tacplus-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestPKI(t, dir)
+
+	config, err := ServerTLSConfig(filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// The secrets differ, packets are not obfuscated over TLS
+	connHandler := &ServerConnHandler{Handler: testHandler{status: AuthorStatusPassAdd}, ConnConfig: ConnConfig{Secret: []byte("server")}}
+
+	go (&Server{ServeConn: connHandler.Serve}).Serve(tls.NewListener(l, config))
+
+	addr := l.Addr().(*net.TCPAddr)
+
+	tests := []struct {
+		tls     TLSInfo
+		correct bool
+	}{
+		{TLSInfo{Enabled: true, CA: "ca.pem", Cert: "client.pem", Key: "client.key"}, true},
+		{TLSInfo{Enabled: true, CA: "other.pem", Cert: "client.pem", Key: "client.key"}, false},
+		{TLSInfo{Enabled: true, CA: "ca.pem", Cert: "client.pem", Key: "client.key", ServerName: "other"}, false},
+		{TLSInfo{Enabled: true, CA: "ca.pem"}, false},
+		{TLSInfo{Enabled: true, CA: "missing.pem"}, false},
+		{TLSInfo{}, false},
+	}
+
+	for _, test := range tests {
+
+		info := test.tls
+		for _, path := range []*string{&info.CA, &info.Cert, &info.Key} {
+			if *path != "" {
+				*path = filepath.Join(dir, *path)
+			}
+		}
+
+		client := CreateClientFromInfo(&TacacsInfo{IP: addr.IP.String(), Port: addr.Port, Password: "client", Timeout: 1, TLS: info})
+
+		response, err := client.SendAuthorRequest(context.Background(), &AuthorRequest{User: "admin"})
+
+		if result := err == nil && response.Status == AuthorStatusPassAdd; result != test.correct {
+			t.Errorf("Result was incorrect for %+v, got: %+v (%v), want success: %t.", test.tls, response, err, test.correct)
+		}
+	}
+}
+
+func TestGetTLSInfo(t *testing.T) {
+
+	info := getTLSInfo(map[string]string{"tls": "true", "tls_ca": "/etc/ssl/ca.pem"})
+
+	if !info.Enabled || info.CA != "/etc/ssl/ca.pem" || info.Cert != "" {
+		t.Errorf("Result was incorrect, got: %+v, want: TLS with the CA /etc/ssl/ca.pem.", info)
+	}
+
+	if info := getTLSInfo(map[string]string{"tls_ca": "/etc/ssl/ca.pem"}); info.Enabled {
+		t.Errorf("Result was incorrect, got: %+v, want: TLS disabled.", info)
+	}
+}